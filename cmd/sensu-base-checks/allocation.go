@@ -0,0 +1,249 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/julian7/sensu-base-checks/measurements"
+	"github.com/julian7/sensu-base-checks/metrics"
+	"github.com/julian7/sensulib"
+	"github.com/spf13/cobra"
+)
+
+type allocationConfig struct {
+	fs        *measurements.Filesystem
+	DWarn     float64
+	DCrit     float64
+	MWarn     float64
+	MCrit     float64
+	Lvs       string
+	ThinPools []string
+	Metrics   bool
+	run       measurements.CommandRunner
+	sysfs     string
+	log       *metrics.Metrics
+}
+
+func allocationCmd() *cobra.Command {
+	config := &allocationConfig{
+		fs:    &measurements.Filesystem{},
+		run:   measurements.ExecRunner,
+		sysfs: measurements.BtrfsSysfs,
+	}
+	cmd := sensulib.NewCommand(
+		config,
+		"allocation",
+		"LVM thin pool and btrfs allocation check",
+		`Checks for data and metadata allocation of LVM thin pools and btrfs filesystems
+
+On LVM thin pools and btrfs, filesystem statistics don't show whether data or
+metadata allocation is running out. This check inspects thin pools of the
+selected filesystems' thin volumes (using lvs), and chunk allocation of the
+selected btrfs filesystems (using sysfs).
+`,
+	)
+	flags := cmd.Flags()
+	config.fs.SetFlags(flags)
+	flags.Float64VarP(&config.DWarn, "dwarn", "w", 80.0, "Warn if PERCENT or more of data space is used; (0,100]")
+	flags.Float64VarP(&config.DCrit, "dcrit", "c", 90.0, "Critical if PERCENT or more of data space is used; (0,100]")
+	flags.Float64VarP(&config.MWarn, "mwarn", "W", 80.0, "Warn if PERCENT or more of metadata space is used; (0,100]")
+	flags.Float64VarP(&config.MCrit, "mcrit", "C", 90.0, "Critical if PERCENT or more of metadata space is used; (0,100]")
+	flags.StringVar(&config.Lvs, "lvs", "lvs", "lvs command, with optional arguments (like \"sudo lvs\"); empty disables LVM checks")
+	flags.StringSliceVar(&config.ThinPools, "thinpool", nil, "Check LVM thin pools in VG/LV form, even without mounted thin volumes")
+	flags.BoolVar(&config.Metrics, "metrics", false, "Output measurements in OpenTSDB format")
+
+	return cmd
+}
+
+func (conf *allocationConfig) check() error {
+	if err := conf.fs.Check(); err != nil {
+		return err
+	}
+
+	if conf.Metrics {
+		return nil
+	}
+
+	checks := []struct {
+		name   string
+		check  bool
+		errstr string
+	}{
+		{"dwarn", conf.DWarn <= 0, "higher than 0"},
+		{"dwarn", conf.DWarn > 100, "at most 100"},
+		{"dcrit", conf.DCrit <= 0, "higher than 0"},
+		{"dcrit", conf.DCrit > 100, "at most 100"},
+		{"dcrit", conf.DCrit <= conf.DWarn, "should be higher than --dwarn"},
+		{"mwarn", conf.MWarn <= 0, "higher than 0"},
+		{"mwarn", conf.MWarn > 100, "at most 100"},
+		{"mcrit", conf.MCrit <= 0, "higher than 0"},
+		{"mcrit", conf.MCrit > 100, "at most 100"},
+		{"mcrit", conf.MCrit <= conf.MWarn, "should be higher than --mwarn"},
+	}
+
+	for _, check := range checks {
+		if check.check {
+			return fmt.Errorf("--%s should be %s", check.name, check.errstr)
+		}
+	}
+
+	return nil
+}
+
+func (conf *allocationConfig) Run(cmd *cobra.Command, args []string) error {
+	if err := conf.check(); err != nil {
+		return sensulib.Unknown(err)
+	}
+
+	// lvs failures matter only if thin pools are requested, or if there
+	// might be thin volumes among the selected filesystems
+	pools, poolsByDevice, lvsErr := conf.thinPools()
+	if lvsErr != nil && len(conf.ThinPools) > 0 {
+		return sensulib.Unknown(lvsErr)
+	}
+
+	allocs, err := measurements.BtrfsAllocations(conf.sysfs)
+	if err != nil {
+		return sensulib.Unknown(fmt.Errorf("cannot read btrfs allocation: %w", err))
+	}
+
+	if conf.Metrics {
+		conf.log = metrics.New("allocation")
+	}
+
+	errs := sensulib.NewErrors()
+	seenPools := map[string]bool{}
+	seenFS := map[string]bool{}
+
+	for _, name := range conf.ThinPools {
+		pool, ok := pools[name]
+		if !ok {
+			errs.Add(sensulib.Crit(fmt.Errorf("thin pool %s not found", name)))
+			continue
+		}
+
+		seenPools[name] = true
+
+		errs.Add(conf.checkThinPool(pool))
+	}
+
+	if err := conf.fs.ForEach(func(part *measurements.Partition) {
+		if lvsErr != nil && strings.HasPrefix(blockDevice(part.Device), "dm-") {
+			errs.Add(sensulib.Unknown(fmt.Errorf("cannot check thin pool of %s: %w", part.Name(), lvsErr)))
+		}
+
+		if pool, ok := poolsByDevice[part.Device]; ok && !seenPools[pool.Name] {
+			seenPools[pool.Name] = true
+
+			errs.Add(conf.checkThinPool(pool))
+		}
+
+		if part.Fstype != "btrfs" {
+			return
+		}
+
+		alloc, ok := allocs[blockDevice(part.Device)]
+		if !ok || seenFS[alloc.UUID] {
+			return
+		}
+
+		seenFS[alloc.UUID] = true
+
+		errs.Add(conf.checkBtrfs(part, alloc))
+	}); err != nil {
+		return err
+	}
+
+	if conf.Metrics {
+		return errs.Return(nil)
+	}
+
+	return errs.Return(sensulib.Ok(fmt.Errorf(
+		"%d thin pools and %d btrfs filesystems are under %s data and %s metadata usage",
+		len(seenPools),
+		len(seenFS),
+		sensulib.PercentToHuman(conf.DWarn, 1),
+		sensulib.PercentToHuman(conf.MWarn, 1),
+	)))
+}
+
+func (conf *allocationConfig) thinPools() (map[string]*measurements.ThinPool, map[string]*measurements.ThinPool, error) {
+	lvs := strings.Fields(conf.Lvs)
+	if len(lvs) == 0 {
+		return map[string]*measurements.ThinPool{}, map[string]*measurements.ThinPool{}, nil
+	}
+
+	pools, byDevice, err := measurements.ThinPools(conf.run, lvs)
+	if errors.Is(err, exec.ErrNotFound) && len(conf.ThinPools) == 0 {
+		// no LVM installed
+		return map[string]*measurements.ThinPool{}, map[string]*measurements.ThinPool{}, nil
+	}
+
+	return pools, byDevice, err
+}
+
+// blockDevice returns kernel name of a block device (like dm-0 for /dev/mapper/vg-lv)
+func blockDevice(device string) string {
+	if resolved, err := filepath.EvalSymlinks(device); err == nil {
+		device = resolved
+	}
+
+	return filepath.Base(device)
+}
+
+func (conf *allocationConfig) checkThinPool(pool *measurements.ThinPool) *sensulib.Error {
+	if conf.Metrics {
+		log := conf.log.With(map[string]string{"pool": pool.Name})
+
+		log.Log("thinpool.data_percent", pool.DataPercent)
+		log.Log("thinpool.metadata_percent", pool.MetadataPercent)
+
+		return nil
+	}
+
+	return conf.checkLevels("thin pool "+pool.Name, pool.DataPercent, pool.MetadataPercent)
+}
+
+func (conf *allocationConfig) checkBtrfs(part *measurements.Partition, alloc *measurements.BtrfsAllocation) *sensulib.Error {
+	if conf.Metrics {
+		log := conf.log.With(map[string]string{"partition": part.Mountpoint, "uuid": alloc.UUID})
+
+		log.Log("btrfs.data_percent", alloc.DataPercent())
+		log.Log("btrfs.metadata_percent", alloc.MetadataPercent())
+		log.Log("btrfs.bytes.total", alloc.DeviceSize)
+		log.Log("btrfs.bytes.unallocated", alloc.Unallocated())
+
+		return nil
+	}
+
+	return conf.checkLevels("btrfs "+part.Name(), alloc.DataPercent(), alloc.MetadataPercent())
+}
+
+func (conf *allocationConfig) checkLevels(name string, data, metadata float64) *sensulib.Error {
+	levels := []struct {
+		kind    string
+		percent float64
+		crit    float64
+		warn    float64
+	}{
+		{"metadata", metadata, conf.MCrit, conf.MWarn},
+		{"data", data, conf.DCrit, conf.DWarn},
+	}
+
+	for _, item := range levels {
+		if item.percent >= item.crit {
+			return sensulib.Crit(fmt.Errorf("%s %s %s usage", name, sensulib.PercentToHuman(item.percent, 2), item.kind))
+		}
+	}
+
+	for _, item := range levels {
+		if item.percent >= item.warn {
+			return sensulib.Warn(fmt.Errorf("%s %s %s usage", name, sensulib.PercentToHuman(item.percent, 2), item.kind))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/julian7/sensu-base-checks/measurements"
+	"github.com/julian7/sensu-base-checks/metrics"
+	"github.com/julian7/sensulib"
+	"github.com/shirou/gopsutil/v3/cpu"
+	"github.com/spf13/cobra"
+)
+
+type cpuConfig struct {
+	Interval   string
+	interval   time.Duration
+	PerCPU     bool
+	Warn       float64
+	Crit       float64
+	IowaitWarn float64
+	IowaitCrit float64
+	StealWarn  float64
+	StealCrit  float64
+	Metrics    bool
+}
+
+func cpuCmd() *cobra.Command {
+	config := &cpuConfig{}
+	cmd := sensulib.NewCommand(
+		config,
+		"cpu",
+		"CPU usage check",
+		`Checks for CPU utilization
+
+CPU times are sampled twice over the sampling interval, and the time spent in
+each state is calculated in percent. Busy time is all the time not spent in
+idle or waiting for I/O. Zero iowait and steal thresholds are not checked.
+
+Load average can be checked with the "load" subcommand.
+`,
+	)
+	flags := cmd.Flags()
+	flags.StringVarP(&config.Interval, "interval", "i", "1s", "Sampling interval")
+	flags.BoolVarP(&config.PerCPU, "percpu", "p", false, "Check and measure each CPU separately, besides total")
+	flags.Float64VarP(&config.Warn, "warn", "w", 85.0, "Warn if CPU is busy PERCENT or more of the time; (0,100]")
+	flags.Float64VarP(&config.Crit, "crit", "c", 95.0, "Critical if CPU is busy PERCENT or more of the time; (0,100]")
+	flags.Float64Var(&config.IowaitWarn, "iowait-warn", 0, "Warn if CPU waits for I/O PERCENT or more of the time")
+	flags.Float64Var(&config.IowaitCrit, "iowait-crit", 0, "Critical if CPU waits for I/O PERCENT or more of the time")
+	flags.Float64Var(&config.StealWarn, "steal-warn", 0, "Warn if PERCENT or more of CPU time is stolen by the hypervisor")
+	flags.Float64Var(&config.StealCrit, "steal-crit", 0, "Critical if PERCENT or more of CPU time is stolen by the hypervisor")
+	flags.BoolVar(&config.Metrics, "metrics", false, "Output measurements in OpenTSDB format")
+
+	cmd.AddCommand(loadCmd())
+
+	return cmd
+}
+
+func (conf *cpuConfig) check() error {
+	var err error
+
+	conf.interval, err = time.ParseDuration(conf.Interval)
+	if err != nil {
+		return fmt.Errorf("cannot parse --interval: %w", err)
+	}
+
+	if conf.interval <= 0 {
+		return errors.New("--interval should be set")
+	}
+
+	if conf.Metrics {
+		return nil
+	}
+
+	checks := []struct {
+		name   string
+		check  bool
+		errstr string
+	}{
+		{"warn", conf.Warn <= 0, "higher than 0"},
+		{"warn", conf.Warn > 100, "at most 100"},
+		{"crit", conf.Crit <= 0, "higher than 0"},
+		{"crit", conf.Crit > 100, "at most 100"},
+		{"crit", conf.Crit <= conf.Warn, "should be higher than --warn"},
+		{"iowait-warn", conf.IowaitWarn < 0 || conf.IowaitWarn > 100, "between 0 and 100"},
+		{"iowait-crit", conf.IowaitCrit < 0 || conf.IowaitCrit > 100, "between 0 and 100"},
+		{
+			"iowait-crit",
+			conf.IowaitWarn > 0 && conf.IowaitCrit > 0 && conf.IowaitCrit <= conf.IowaitWarn,
+			"should be higher than --iowait-warn",
+		},
+		{"steal-warn", conf.StealWarn < 0 || conf.StealWarn > 100, "between 0 and 100"},
+		{"steal-crit", conf.StealCrit < 0 || conf.StealCrit > 100, "between 0 and 100"},
+		{
+			"steal-crit",
+			conf.StealWarn > 0 && conf.StealCrit > 0 && conf.StealCrit <= conf.StealWarn,
+			"should be higher than --steal-warn",
+		},
+	}
+
+	for _, check := range checks {
+		if check.check {
+			return fmt.Errorf("--%s should be %s", check.name, check.errstr)
+		}
+	}
+
+	return nil
+}
+
+func (conf *cpuConfig) Run(cmd *cobra.Command, args []string) error {
+	if err := conf.check(); err != nil {
+		return sensulib.Unknown(err)
+	}
+
+	usages, err := conf.sample()
+	if err != nil {
+		return sensulib.Unknown(err)
+	}
+
+	if conf.Metrics {
+		conf.printMetrics(usages)
+		return nil
+	}
+
+	errs := sensulib.NewErrors()
+
+	for _, usage := range usages {
+		errs.Add(conf.checkUsage(usage))
+	}
+
+	return errs.Return(sensulib.Ok(fmt.Errorf(
+		"CPU is %s busy (%s user, %s system, %s iowait, %s steal)",
+		sensulib.PercentToHuman(usages[0].Busy(), 1),
+		sensulib.PercentToHuman(usages[0].User, 1),
+		sensulib.PercentToHuman(usages[0].System, 1),
+		sensulib.PercentToHuman(usages[0].Iowait, 1),
+		sensulib.PercentToHuman(usages[0].Steal, 1),
+	)))
+}
+
+// sample returns total CPU usage, followed by per-CPU usages if requested
+func (conf *cpuConfig) sample() ([]*measurements.CPUUsage, error) {
+	times := func() ([]cpu.TimesStat, error) {
+		total, err := cpu.Times(false)
+		if err != nil || !conf.PerCPU {
+			return total, err
+		}
+
+		percpu, err := cpu.Times(true)
+
+		return append(total, percpu...), err
+	}
+
+	prev, err := times()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read CPU times: %w", err)
+	}
+
+	time.Sleep(conf.interval)
+
+	cur, err := times()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read CPU times: %w", err)
+	}
+
+	if len(prev) == 0 || len(prev) != len(cur) {
+		return nil, errors.New("cannot read CPU times: number of CPUs changed")
+	}
+
+	usages := make([]*measurements.CPUUsage, len(cur))
+	for i := range cur {
+		usages[i] = measurements.CPUUsageBetween(prev[i], cur[i])
+	}
+
+	return usages, nil
+}
+
+func (conf *cpuConfig) checkUsage(usage *measurements.CPUUsage) *sensulib.Error {
+	var crit, warn []string
+
+	for _, item := range []struct {
+		name    string
+		percent float64
+		warn    float64
+		crit    float64
+	}{
+		{"busy", usage.Busy(), conf.Warn, conf.Crit},
+		{"iowait", usage.Iowait, conf.IowaitWarn, conf.IowaitCrit},
+		{"steal", usage.Steal, conf.StealWarn, conf.StealCrit},
+	} {
+		msg := fmt.Sprintf("%s %s", sensulib.PercentToHuman(item.percent, 1), item.name)
+
+		switch {
+		case item.crit > 0 && item.percent >= item.crit:
+			crit = append(crit, msg)
+		case item.warn > 0 && item.percent >= item.warn:
+			warn = append(warn, msg)
+		}
+	}
+
+	switch {
+	case len(crit) > 0:
+		return sensulib.Crit(fmt.Errorf("%s is %s", usage.CPU, strings.Join(append(crit, warn...), ", ")))
+	case len(warn) > 0:
+		return sensulib.Warn(fmt.Errorf("%s is %s", usage.CPU, strings.Join(warn, ", ")))
+	}
+
+	return nil
+}
+
+func (conf *cpuConfig) printMetrics(usages []*measurements.CPUUsage) {
+	log := metrics.New("cpu")
+
+	for _, usage := range usages {
+		cpulog := log.With(map[string]string{"cpu": usage.CPU})
+
+		cpulog.Log("percent.busy", usage.Busy())
+		cpulog.Log("percent.user", usage.User)
+		cpulog.Log("percent.nice", usage.Nice)
+		cpulog.Log("percent.system", usage.System)
+		cpulog.Log("percent.idle", usage.Idle)
+		cpulog.Log("percent.iowait", usage.Iowait)
+		cpulog.Log("percent.irq", usage.Irq)
+		cpulog.Log("percent.softirq", usage.Softirq)
+		cpulog.Log("percent.steal", usage.Steal)
+	}
+}
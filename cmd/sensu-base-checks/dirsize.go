@@ -0,0 +1,165 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/julian7/sensu-base-checks/measurements"
+	"github.com/julian7/sensu-base-checks/metrics"
+	"github.com/julian7/sensulib"
+	"github.com/spf13/cobra"
+)
+
+type dirsizeConfig struct {
+	walker  *measurements.DirWalker
+	Dirs    []string
+	BWarnS  string
+	bwarn   uint64
+	BCritS  string
+	bcrit   uint64
+	FWarn   uint64
+	FCrit   uint64
+	Metrics bool
+	log     *metrics.Metrics
+}
+
+func dirsizeCmd() *cobra.Command {
+	config := &dirsizeConfig{walker: &measurements.DirWalker{}}
+	cmd := sensulib.NewCommand(
+		config,
+		"dirsize",
+		"Directory size check",
+		`Checks for total size and file count of directory trees.
+
+Sizes can be provided with binary units (eg. 512k, 10M, 1.5GiB). Zero or empty
+thresholds are not checked.`,
+	)
+	flags := cmd.Flags()
+	config.walker.SetFlags(flags)
+	flags.StringSliceVarP(&config.Dirs, "dir", "d", nil, "Directory to check")
+	flags.StringVarP(&config.BWarnS, "bwarn", "w", "", "Warn if directory tree is at least SIZE")
+	flags.StringVarP(&config.BCritS, "bcrit", "c", "", "Critical if directory tree is at least SIZE")
+	flags.Uint64VarP(&config.FWarn, "fwarn", "W", 0, "Warn if directory tree has at least COUNT files")
+	flags.Uint64VarP(&config.FCrit, "fcrit", "C", 0, "Critical if directory tree has at least COUNT files")
+	flags.BoolVar(&config.Metrics, "metrics", false, "Output measurements in OpenTSDB format")
+
+	return cmd
+}
+
+func (conf *dirsizeConfig) check() error {
+	var err error
+
+	if err := conf.walker.Check(); err != nil {
+		return err
+	}
+
+	if len(conf.Dirs) == 0 {
+		return errors.New("--dir should be set")
+	}
+
+	for _, item := range []struct {
+		name   string
+		source string
+		target *uint64
+	}{
+		{"bwarn", conf.BWarnS, &conf.bwarn},
+		{"bcrit", conf.BCritS, &conf.bcrit},
+	} {
+		if len(item.source) == 0 {
+			continue
+		}
+
+		*item.target, err = parseSize(item.source)
+		if err != nil {
+			return fmt.Errorf("parsing --%s: %w", item.name, err)
+		}
+	}
+
+	if conf.Metrics {
+		return nil
+	}
+
+	for _, item := range []struct {
+		name        string
+		requirement bool
+	}{
+		{"--bcrit should be higher than --bwarn", conf.bwarn == 0 || conf.bcrit == 0 || conf.bcrit > conf.bwarn},
+		{"--fcrit should be higher than --fwarn", conf.FWarn == 0 || conf.FCrit == 0 || conf.FCrit > conf.FWarn},
+		{
+			"at least one threshold should be set",
+			conf.bwarn > 0 || conf.bcrit > 0 || conf.FWarn > 0 || conf.FCrit > 0,
+		},
+	} {
+		if !item.requirement {
+			return errors.New(item.name)
+		}
+	}
+
+	return nil
+}
+
+func (conf *dirsizeConfig) Run(cmd *cobra.Command, args []string) error {
+	if err := conf.check(); err != nil {
+		return sensulib.Unknown(err)
+	}
+
+	if conf.Metrics {
+		conf.log = metrics.New("dirsize")
+	}
+
+	errs := sensulib.NewErrors()
+
+	for _, dir := range conf.Dirs {
+		st, err := conf.walker.Walk(dir)
+		if err != nil {
+			errs.Add(sensulib.Crit(fmt.Errorf("unable to read %s: %w", dir, err)))
+			continue
+		}
+
+		if conf.Metrics {
+			conf.measureDir(dir, st)
+			continue
+		}
+
+		errs.Add(conf.checkDir(dir, st))
+	}
+
+	if conf.Metrics {
+		return errs.Return(nil)
+	}
+
+	return errs.Return(sensulib.Ok(fmt.Errorf("all %d directories are within limits", len(conf.Dirs))))
+}
+
+func (conf *dirsizeConfig) checkDir(dir string, st *measurements.DirStat) *sensulib.Error {
+	size := fmt.Sprintf("%s in %d files", sensulib.SizeToHuman(st.Bytes), st.Files)
+
+	switch {
+	case conf.bcrit > 0 && st.Bytes >= conf.bcrit:
+		return sensulib.Crit(fmt.Errorf("%s has %s, limit is %s", dir, size, sensulib.SizeToHuman(conf.bcrit)))
+	case conf.FCrit > 0 && st.Files >= conf.FCrit:
+		return sensulib.Crit(fmt.Errorf("%s has %s, limit is %d files", dir, size, conf.FCrit))
+	case conf.bwarn > 0 && st.Bytes >= conf.bwarn:
+		return sensulib.Warn(fmt.Errorf("%s has %s, limit is %s", dir, size, sensulib.SizeToHuman(conf.bwarn)))
+	case conf.FWarn > 0 && st.Files >= conf.FWarn:
+		return sensulib.Warn(fmt.Errorf("%s has %s, limit is %d files", dir, size, conf.FWarn))
+	}
+
+	for _, err := range st.Errors {
+		if !errors.Is(err, os.ErrPermission) {
+			return sensulib.Warn(fmt.Errorf("%s has %s, with %d read errors (first: %v)", dir, size, len(st.Errors), err))
+		}
+	}
+
+	return nil
+}
+
+func (conf *dirsizeConfig) measureDir(dir string, st *measurements.DirStat) {
+	log := conf.log.With(map[string]string{"path": dir})
+
+	log.Log("bytes.total", st.Bytes)
+	log.Log("files.total", st.Files)
+	log.Log("dirs.total", st.Dirs)
+	log.Log("errors.total", len(st.Errors))
+}
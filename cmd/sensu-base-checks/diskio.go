@@ -0,0 +1,179 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/julian7/sensu-base-checks/measurements"
+	"github.com/julian7/sensu-base-checks/metrics"
+	"github.com/julian7/sensulib"
+	"github.com/spf13/cobra"
+)
+
+type diskioConfig struct {
+	dev     *measurements.DiskIO
+	UWarn   float64
+	UCrit   float64
+	LWarnS  string
+	lwarn   time.Duration
+	LCritS  string
+	lcrit   time.Duration
+	Metrics bool
+}
+
+func diskioCmd() *cobra.Command {
+	config := &diskioConfig{dev: &measurements.DiskIO{}}
+	cmd := sensulib.NewCommand(
+		config,
+		"diskio",
+		"Disk I/O check",
+		`Checks for block device utilization and latency
+
+I/O counters are sampled twice over the sampling interval, or, if a state file
+is provided, they are compared to counters saved by the previous run.
+`,
+	)
+	flags := cmd.Flags()
+	config.dev.SetFlags(flags)
+	flags.Float64VarP(&config.UWarn, "uwarn", "w", 85.0, "Warn if device is busy PERCENT or more of the time; (0,100]")
+	flags.Float64VarP(&config.UCrit, "ucrit", "c", 95.0, "Critical if device is busy PERCENT or more of the time; (0,100]")
+	flags.StringVarP(&config.LWarnS, "lwarn", "W", "", "Warn if average I/O latency is at least this duration")
+	flags.StringVarP(&config.LCritS, "lcrit", "C", "", "Critical if average I/O latency is at least this duration")
+	flags.BoolVar(&config.Metrics, "metrics", false, "Output measurements in OpenTSDB format")
+
+	return cmd
+}
+
+func (conf *diskioConfig) check() error {
+	var err error
+
+	if err := conf.dev.Check(); err != nil {
+		return err
+	}
+
+	for _, item := range []struct {
+		name   string
+		source string
+		target *time.Duration
+	}{
+		{"lwarn", conf.LWarnS, &conf.lwarn},
+		{"lcrit", conf.LCritS, &conf.lcrit},
+	} {
+		if len(item.source) == 0 {
+			continue
+		}
+
+		*item.target, err = time.ParseDuration(item.source)
+		if err != nil {
+			return fmt.Errorf("parsing --%s: %w", item.name, err)
+		}
+	}
+
+	if conf.Metrics {
+		return nil
+	}
+
+	for _, item := range []struct {
+		name        string
+		requirement bool
+	}{
+		{"--uwarn should be between 0 and 100", conf.UWarn > 0 && conf.UWarn <= 100},
+		{"--ucrit should be between 0 and 100", conf.UCrit > 0 && conf.UCrit <= 100},
+		{"--ucrit should be higher than --uwarn", conf.UCrit > conf.UWarn},
+		{"--lcrit should be higher than --lwarn", conf.lwarn == 0 || conf.lcrit == 0 || conf.lcrit > conf.lwarn},
+	} {
+		if !item.requirement {
+			return errors.New(item.name)
+		}
+	}
+
+	return nil
+}
+
+func (conf *diskioConfig) Run(cmd *cobra.Command, args []string) error {
+	if err := conf.check(); err != nil {
+		return sensulib.Unknown(err)
+	}
+
+	rates, err := conf.dev.Rates()
+	if err != nil {
+		return sensulib.Unknown(err)
+	}
+
+	names := make([]string, 0, len(rates))
+	for name := range rates {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	if conf.Metrics {
+		conf.printMetrics(names, rates)
+		return nil
+	}
+
+	errs := sensulib.NewErrors()
+
+	for _, name := range names {
+		errs.Add(conf.checkDevice(name, rates[name]))
+	}
+
+	return errs.Return(sensulib.Ok(fmt.Errorf(
+		"all %d devices are under %s utilization",
+		len(names),
+		sensulib.PercentToHuman(conf.UWarn, 1),
+	)))
+}
+
+func (conf *diskioConfig) checkDevice(name string, rate *measurements.DiskIORate) *sensulib.Error {
+	var crit, warn []string
+
+	util := fmt.Sprintf(
+		"%s utilized (%.1f r/s, %.1f w/s)",
+		sensulib.PercentToHuman(rate.Util, 1),
+		rate.ReadIOPS,
+		rate.WriteIOPS,
+	)
+
+	switch {
+	case rate.Util >= conf.UCrit:
+		crit = append(crit, util)
+	case rate.Util >= conf.UWarn:
+		warn = append(warn, util)
+	}
+
+	switch {
+	case conf.lcrit > 0 && rate.Await >= conf.lcrit:
+		crit = append(crit, fmt.Sprintf("%s average I/O latency (level %s)", rate.Await, conf.lcrit))
+	case conf.lwarn > 0 && rate.Await >= conf.lwarn:
+		warn = append(warn, fmt.Sprintf("%s average I/O latency (level %s)", rate.Await, conf.lwarn))
+	}
+
+	switch {
+	case len(crit) > 0:
+		return sensulib.Crit(fmt.Errorf("%s is %s", name, strings.Join(append(crit, warn...), ", ")))
+	case len(warn) > 0:
+		return sensulib.Warn(fmt.Errorf("%s is %s", name, strings.Join(warn, ", ")))
+	}
+
+	return nil
+}
+
+func (conf *diskioConfig) printMetrics(names []string, rates map[string]*measurements.DiskIORate) {
+	log := metrics.New("diskio")
+
+	for _, name := range names {
+		rate := rates[name]
+		devlog := log.With(map[string]string{"dev": name})
+
+		devlog.Log("iops.read", rate.ReadIOPS)
+		devlog.Log("iops.write", rate.WriteIOPS)
+		devlog.Log("throughput.read", rate.ReadBytes)
+		devlog.Log("throughput.write", rate.WriteBytes)
+		devlog.Log("time.await", rate.Await.Microseconds())
+		devlog.Log("util", rate.Util)
+	}
+}
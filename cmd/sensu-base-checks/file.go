@@ -0,0 +1,206 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/julian7/sensu-base-checks/measurements"
+	"github.com/julian7/sensu-base-checks/metrics"
+	"github.com/julian7/sensulib"
+	"github.com/karrick/tparse"
+	"github.com/spf13/cobra"
+)
+
+type fileConfig struct {
+	Files    []string
+	MinAge   string
+	MaxAge   string
+	MinSizeS string
+	MaxSizeS string
+	MatchS   string
+	Metrics  bool
+	conds    measurements.FileConditions
+	log      *metrics.Metrics
+}
+
+func fileCmd() *cobra.Command {
+	config := &fileConfig{}
+	cmd := sensulib.NewCommand(
+		config,
+		"file",
+		"File check",
+		`Checks for files' existence, age, size, and content
+
+Files can be provided as glob patterns, and all matching files are checked.
+Returns Critical if a pattern has no matches, or any of the matching files
+fail any conditions.
+
+Age can be provided in long range too (like d, w, mo), sizes can be provided
+with binary units (eg. 512k, 10M, 1.5GiB).
+`,
+	)
+	flags := cmd.Flags()
+	flags.StringSliceVarP(&config.Files, "file", "f", nil, "File path or glob pattern to check")
+	flags.StringVar(&config.MinAge, "min-age", "", "Critical if file is modified more recently than this duration")
+	flags.StringVarP(&config.MaxAge, "max-age", "a", "", "Critical if file is not modified for this duration")
+	flags.StringVar(&config.MinSizeS, "min-size", "", "Critical if file is smaller than SIZE")
+	flags.StringVar(&config.MaxSizeS, "max-size", "", "Critical if file is larger than SIZE")
+	flags.StringVarP(&config.MatchS, "match", "m", "", "Critical if file content doesn't match regular expression")
+	flags.BoolVar(&config.Metrics, "metrics", false, "Output measurements in OpenTSDB format")
+
+	return cmd
+}
+
+func (conf *fileConfig) check() error {
+	var err error
+
+	if len(conf.Files) == 0 {
+		return errors.New("--file should be set")
+	}
+
+	for _, pattern := range conf.Files {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("cannot interpret --file %q: %w", pattern, err)
+		}
+	}
+
+	for _, item := range []struct {
+		name   string
+		source string
+		target *time.Duration
+	}{
+		{"min-age", conf.MinAge, &conf.conds.MinAge},
+		{"max-age", conf.MaxAge, &conf.conds.MaxAge},
+	} {
+		if len(item.source) == 0 {
+			continue
+		}
+
+		since, err := tparse.ParseNow(time.RFC3339, "now-"+item.source)
+		if err != nil {
+			return fmt.Errorf("cannot parse --%s: %w", item.name, err)
+		}
+
+		*item.target = time.Since(since)
+	}
+
+	for _, item := range []struct {
+		name   string
+		source string
+		target *uint64
+	}{
+		{"min-size", conf.MinSizeS, &conf.conds.MinSize},
+		{"max-size", conf.MaxSizeS, &conf.conds.MaxSize},
+	} {
+		if len(item.source) == 0 {
+			continue
+		}
+
+		*item.target, err = parseSize(item.source)
+		if err != nil {
+			return fmt.Errorf("cannot parse --%s: %w", item.name, err)
+		}
+	}
+
+	if len(conf.MatchS) > 0 {
+		conf.conds.Match, err = regexp.Compile(conf.MatchS)
+		if err != nil {
+			return fmt.Errorf("cannot interpret regexp from --match: %w", err)
+		}
+	}
+
+	tests := []struct {
+		opt   string
+		check bool
+		err   string
+	}{
+		{
+			"max-age",
+			conf.conds.MinAge > 0 && conf.conds.MaxAge > 0 && conf.conds.MaxAge <= conf.conds.MinAge,
+			"should be longer than --min-age",
+		},
+		{
+			"max-size",
+			conf.conds.MaxSize > 0 && conf.conds.MaxSize < conf.conds.MinSize,
+			"should be at least --min-size",
+		},
+	}
+	for _, test := range tests {
+		if test.check {
+			return fmt.Errorf("--%s %s", test.opt, test.err)
+		}
+	}
+
+	return nil
+}
+
+func (conf *fileConfig) Run(cmd *cobra.Command, args []string) error {
+	if err := conf.check(); err != nil {
+		return sensulib.Unknown(err)
+	}
+
+	if conf.Metrics {
+		conf.log = metrics.New("file")
+	}
+
+	errs := sensulib.NewErrors()
+	count := 0
+
+	for _, pattern := range conf.Files {
+		matches, err := measurements.GlobFiles(pattern)
+		if errors.Is(err, measurements.ErrNoFiles) {
+			errs.Add(sensulib.Crit(err))
+			continue
+		}
+
+		if err != nil {
+			errs.Add(sensulib.Unknown(err))
+			continue
+		}
+
+		for _, path := range matches {
+			count++
+
+			errs.Add(conf.checkFile(path))
+		}
+	}
+
+	if conf.Metrics {
+		return errs.Return(nil)
+	}
+
+	return errs.Return(sensulib.Ok(fmt.Errorf("all %d files are matching conditions", count)))
+}
+
+func (conf *fileConfig) checkFile(path string) *sensulib.Error {
+	if conf.Metrics {
+		return conf.measureFile(path)
+	}
+
+	if err := conf.conds.Check(path); err != nil {
+		return sensulib.Crit(err)
+	}
+
+	return nil
+}
+
+func (conf *fileConfig) measureFile(path string) *sensulib.Error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return sensulib.Crit(fmt.Errorf("unable to read %s: %v", path, err))
+	}
+
+	log := conf.log.With(map[string]string{"path": path})
+
+	log.Log("age", int64(time.Since(info.ModTime()).Seconds()))
+
+	if info.Mode().IsRegular() {
+		log.Log("bytes", info.Size())
+	}
+
+	return nil
+}
@@ -15,6 +15,8 @@ import (
 
 type filesystemConfig struct {
 	fs      *measurements.Filesystem
+	mntopts *measurements.MountOptions
+	probe   *measurements.WriteProbe
 	BWarn   float64
 	BCrit   float64
 	IWarn   float64
@@ -23,11 +25,22 @@ type filesystemConfig struct {
 	Metrics bool
 	Minimum int
 	Normal  int
+	Space   string
 	log     *metrics.Metrics
 }
 
+// Space modes of filesystem checks
+const (
+	spaceAvail = "avail"
+	spaceRaw   = "raw"
+)
+
 func filesystemCmd() *cobra.Command {
-	config := &filesystemConfig{fs: &measurements.Filesystem{}}
+	config := &filesystemConfig{
+		fs:      &measurements.Filesystem{},
+		mntopts: &measurements.MountOptions{},
+		probe:   &measurements.WriteProbe{},
+	}
 	cmd := sensulib.NewCommand(
 		config,
 		"filesystem",
@@ -36,6 +49,8 @@ func filesystemCmd() *cobra.Command {
 	)
 	flags := cmd.Flags()
 	config.fs.SetFlags(flags)
+	config.mntopts.SetFlags(flags)
+	config.probe.SetFlags(flags)
 	flags.Float64VarP(&config.BWarn, "bwarn", "w", 85.0, "Warn if PERCENT or more of filesystem full; (0,100]")
 	flags.Float64VarP(&config.BCrit, "bcrit", "c", 95.0, "Critical if PERCENT or more of filesystem full; (0,100]")
 	flags.Float64VarP(&config.IWarn, "iwarn", "W", 85.0, "Warn if PERCENT or more of inodes used; (0,100]")
@@ -45,6 +60,8 @@ func filesystemCmd() *cobra.Command {
 	flags.IntVarP(&config.Minimum, "minimum", "l", 100, "Minimum size to adjust (ing GB)")
 	flags.IntVarP(&config.Normal, "normal", "n", 20, "Levels are not adapted for filesystems of exactly this size (GB)."+
 		" Levels reduced below this size, and raised for larger sizes.")
+	flags.StringVar(&config.Space, "space", spaceAvail, "Check storage thresholds against space available for"+
+		" unprivileged users (avail), or against all space, including root-reserved space (raw)")
 
 	return cmd
 }
@@ -54,6 +71,18 @@ func (conf *filesystemConfig) check() error {
 		return err
 	}
 
+	if err := conf.mntopts.Check(); err != nil {
+		return err
+	}
+
+	if err := conf.probe.Check(); err != nil {
+		return err
+	}
+
+	if conf.Space != spaceAvail && conf.Space != spaceRaw {
+		return fmt.Errorf("--space should be either %s or %s", spaceAvail, spaceRaw)
+	}
+
 	if conf.Metrics {
 		return nil
 	}
@@ -94,11 +123,8 @@ func (conf *filesystemConfig) Run(cmd *cobra.Command, args []string) error {
 		return sensulib.Unknown(err)
 	}
 
-	checkFn := conf.checkPartition
-
 	if conf.Metrics {
 		conf.log = metrics.New("filesystem")
-		checkFn = conf.measurePartition
 	} else {
 		errDefault = sensulib.Ok(
 			fmt.Errorf(
@@ -111,12 +137,28 @@ func (conf *filesystemConfig) Run(cmd *cobra.Command, args []string) error {
 
 	errs := sensulib.NewErrors()
 
-	if err := conf.fs.ForEach(func(part *disk.PartitionStat) {
-		errs.Add(checkFn(part))
+	if err := conf.fs.ForEach(func(part *measurements.Partition) {
+		if conf.Metrics {
+			errs.Add(conf.measurePartition(part))
+
+			return
+		}
+
+		conf.checkPartition(part, errs)
 	}); err != nil {
 		return err
 	}
 
+	if !conf.Metrics && conf.mntopts.HasRules() {
+		if err := conf.fs.ForEachMounted(func(part *disk.PartitionStat) {
+			errs.Add(conf.mntopts.CheckRules(part))
+		}); err != nil {
+			return err
+		}
+
+		errs.Add(conf.mntopts.CheckMissing())
+	}
+
 	return errs.Return(errDefault)
 }
 
@@ -124,21 +166,41 @@ func adjustLevel(total, normal uint64, magic, percent float64) float64 {
 	return 100 - ((100 - percent) * math.Pow(float64(total/normal), magic-1))
 }
 
-func (conf *filesystemConfig) checkPartition(part *disk.PartitionStat) *sensulib.Error {
+// levels returns storage warning and critical levels, adjusted to filesystem size
+func (conf *filesystemConfig) levels(total uint64) (float64, float64) {
+	normal := uint64(conf.Normal) * 1024 * 1024
+	minimum := uint64(conf.Minimum) * 1024 * 1024
+
+	if total <= minimum {
+		return conf.BWarn, conf.BCrit
+	}
+
+	return adjustLevel(total, normal, conf.Magic, conf.BWarn), adjustLevel(total, normal, conf.Magic, conf.BCrit)
+}
+
+// checkPartition adds all issues of a partition to errs
+func (conf *filesystemConfig) checkPartition(part *measurements.Partition, errs *sensulib.Errors) {
 	st, err := disk.Usage(part.Mountpoint)
 	if err != nil {
-		if errors.Is(err, os.ErrPermission) {
-			return nil
+		if !errors.Is(err, os.ErrPermission) {
+			errs.Add(sensulib.Warn(fmt.Errorf("unable to read %s: %v", part.Mountpoint, err)))
 		}
 
-		return sensulib.Warn(fmt.Errorf("unable to read %s: %v", part.Mountpoint, err))
+		return
 	}
 
+	errs.Add(conf.mntopts.CheckReadonly(&part.PartitionStat))
+	errs.Add(conf.checkProbe(part))
+	errs.Add(conf.checkInodes(part, st))
+	errs.Add(conf.checkSpace(part, st))
+}
+
+func (conf *filesystemConfig) checkInodes(part *measurements.Partition, st *disk.UsageStat) *sensulib.Error {
 	if st.InodesTotal > 0 {
 		if st.InodesUsedPercent >= conf.IWarn {
 			err := fmt.Errorf(
 				"%s %s inode usage",
-				part.Mountpoint,
+				part.Name(),
 				sensulib.PercentToHuman(st.InodesUsedPercent, 1),
 			)
 
@@ -150,29 +212,28 @@ func (conf *filesystemConfig) checkPartition(part *disk.PartitionStat) *sensulib
 		}
 	}
 
-	var bcrit, bwarn float64
+	return nil
+}
 
-	normal := uint64(conf.Normal) * 1024 * 1024
-	minimum := uint64(conf.Minimum) * 1024 * 1024
+func (conf *filesystemConfig) checkSpace(part *measurements.Partition, st *disk.UsageStat) *sensulib.Error {
+	bwarn, bcrit := conf.levels(st.Total)
 
-	if st.Total <= minimum {
-		bwarn = conf.BWarn
-		bcrit = conf.BCrit
-	} else {
-		bwarn = adjustLevel(st.Total, normal, conf.Magic, conf.BWarn)
-		bcrit = adjustLevel(st.Total, normal, conf.Magic, conf.BCrit)
+	used := st.UsedPercent
+	if conf.Space == spaceRaw {
+		used = rawUsedPercent(st)
 	}
 
-	if st.UsedPercent >= bwarn {
-		err = fmt.Errorf(
-			"%s %s usage (%s free of %s)",
-			part.Mountpoint,
-			sensulib.PercentToHuman(st.UsedPercent, 2),
+	if used >= bwarn {
+		err := fmt.Errorf(
+			"%s %s usage (%s available, %s free of %s)",
+			part.Name(),
+			sensulib.PercentToHuman(used, 2),
 			sensulib.SizeToHuman(st.Free),
+			sensulib.SizeToHuman(st.Free+reservedBytes(st)),
 			sensulib.SizeToHuman(st.Total),
 		)
 
-		if st.UsedPercent >= bcrit {
+		if used >= bcrit {
 			return sensulib.Crit(err)
 		}
 
@@ -182,7 +243,7 @@ func (conf *filesystemConfig) checkPartition(part *disk.PartitionStat) *sensulib
 	return nil
 }
 
-func (conf *filesystemConfig) measurePartition(part *disk.PartitionStat) *sensulib.Error {
+func (conf *filesystemConfig) measurePartition(part *measurements.Partition) *sensulib.Error {
 	st, err := disk.Usage(part.Mountpoint)
 	if err != nil {
 		if errors.Is(err, os.ErrPermission) {
@@ -198,13 +259,67 @@ func (conf *filesystemConfig) measurePartition(part *disk.PartitionStat) *sensul
 		"partition": part.Mountpoint,
 	})
 
+	bwarn, bcrit := conf.levels(st.Total)
+
 	log.Log("bytes.free", st.Free)
 	log.Log("bytes.total", st.Total)
+	log.Log("bytes.used", st.Used)
+	log.Log("bytes.used_percent", st.UsedPercent)
+	log.Log("bytes.used_percent_raw", rawUsedPercent(st))
+	log.Log("bytes.reserved", reservedBytes(st))
+	log.Log("bytes.free_raw", st.Free+reservedBytes(st))
+	log.Log("bytes.warn_percent", bwarn)
+	log.Log("bytes.crit_percent", bcrit)
 
 	if st.InodesTotal > 0 {
 		log.Log("inodes.free", st.InodesFree)
 		log.Log("inodes.total", st.InodesTotal)
+		log.Log("inodes.used_percent", st.InodesUsedPercent)
+	}
+
+	if conf.probe.Enabled() {
+		latency, err := conf.probe.Probe(part.Mountpoint)
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Log("probe.latency", latency.Microseconds())
+
+			if err != nil {
+				log.Log("probe.error", 1)
+			} else {
+				log.Log("probe.error", 0)
+			}
+		}
 	}
 
 	return nil
 }
+
+func (conf *filesystemConfig) checkProbe(part *measurements.Partition) *sensulib.Error {
+	if !conf.probe.Enabled() {
+		return nil
+	}
+
+	_, err := conf.probe.Probe(part.Mountpoint)
+	if err == nil || errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	return sensulib.Crit(fmt.Errorf("%s is not writable: %v", part.Name(), err))
+}
+
+// reservedBytes returns space available only for root (like ext4's reserved blocks)
+func reservedBytes(st *disk.UsageStat) uint64 {
+	if st.Used+st.Free > st.Total {
+		return 0
+	}
+
+	return st.Total - st.Used - st.Free
+}
+
+// rawUsedPercent returns used percentage of all space, including root-reserved space
+func rawUsedPercent(st *disk.UsageStat) float64 {
+	if st.Total == 0 {
+		return 0
+	}
+
+	return float64(st.Used) / float64(st.Total) * 100
+}
@@ -5,47 +5,87 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptrace"
+	"net/url"
 	"strings"
 	"time"
 
-	"github.com/hako/durafmt"
 	"github.com/jmespath/go-jmespath"
 	"github.com/julian7/sensu-base-checks/measurements"
 	"github.com/julian7/sensu-base-checks/metrics"
 	"github.com/julian7/sensulib"
 	"github.com/karrick/tparse"
+	"github.com/santhosh-tekuri/jsonschema/v5"
 	"github.com/spf13/cobra"
 )
 
 type httpConfig struct {
-	URL       string
-	Timeout   string
-	timeout   time.Duration
-	Headers   []string
-	Insecure  bool
-	Certfile  string
-	CAfile    string
-	Expiry    string
-	expiry    time.Time
-	Method    string
-	Metrics   bool
-	Response  uint
-	Redirect  string
-	UserAgent string
-	Data      string
-	JSONkey   string
-	JSONval   string
-	certList  []*x509.Certificate
-	tracer    *measurements.HTTPTracer
+	URL          string
+	Timeout      string
+	timeout      time.Duration
+	Headers      []string
+	tlsClient    measurements.TLSClient
+	inspector    measurements.TLSInspector
+	ocsp         measurements.OCSPChecker
+	tlsState     *tls.ConnectionState
+	tlsProblems  []measurements.TLSProblem
+	expiry       measurements.CertExpiry
+	Method       string
+	Metrics      bool
+	Response     uint
+	Redirect     string
+	Follow       int
+	FinalURL     string
+	redirects    measurements.RedirectPolicy
+	UserAgent    string
+	Data         string
+	JSONkey      string
+	JSONval      string
+	JSONAsserts  []string
+	jsonAsserts  []*measurements.JSONAssertion
+	JSONSchema   string
+	jsonSchema   *jsonschema.Schema
+	SchemaErrors uint
+	ExpectHeader []string
+	headers      []*measurements.HeaderAssertion
+	Audit        bool
+	HSTSMinAge   string
+	hstsMinAge   time.Duration
+	body         measurements.BodyCheck
+	MaxBytes     string
+	certList     []*x509.Certificate
+	tracer       *measurements.HTTPTracer
+	latencies    []*httpLatency
+}
+
+// httpLatency contains response time thresholds of a request phase
+type httpLatency struct {
+	name    string
+	desc    string
+	warnS   string
+	warn    time.Duration
+	critS   string
+	crit    time.Duration
+	measure func(*measurements.HTTPTracer) time.Duration
+}
+
+func httpLatencies() []*httpLatency {
+	return []*httpLatency{
+		{name: "time", desc: "response", measure: (*measurements.HTTPTracer).Total},
+		{name: "dns", desc: "name resolution", measure: (*measurements.HTTPTracer).DNSTime},
+		{name: "connect", desc: "TCP connection", measure: (*measurements.HTTPTracer).ConnectTime},
+		{name: "tls", desc: "TLS handshake", measure: (*measurements.HTTPTracer).TLSTime},
+		{name: "ttfb", desc: "time to first byte", measure: (*measurements.HTTPTracer).FirstByteTime},
+	}
 }
 
 func httpCmd() *cobra.Command {
-	config := &httpConfig{}
+	config := &httpConfig{latencies: httpLatencies()}
 	cmd := sensulib.NewCommand(
 		config,
 		"http",
@@ -55,30 +95,68 @@ func httpCmd() *cobra.Command {
 This check runs a HTTP query, and inspects return values. Returns
 
 - Unknown on configuration issues,
-- Warning on nearing TLS cert expiry or not matching, but non-error HTTP codes,
+- Warning on nearing TLS cert expiry, slow responses, or not matching, but
+  non-error HTTP codes,
 - Critical on any other cases.
 
 Timeout duration can be provided in short range (eg. ms, s, m, h), cert expiry
 can be provided with longer range too (like d, w, mo).
+
+TLS inspection checks the certificate chain against trusted CAs, hostname,
+key types and sizes, signature algorithms, and negotiated protocol version and
+cipher suite. Problem classes (chain, hostname, key, signature, protocol) have
+separate alert levels. Critical chain or hostname problems abort the request
+before sending it. With --insecure, chain and hostname are not checked.
+
+OCSP checking uses the stapled OCSP response, or queries the certificate's OCSP
+responder. Revoked certificates and must-staple certificates without stapled
+responses are critical, stale responses raise warnings.
+
+JSON assertions either compare a JMESPath expression to a value, or check its
+numeric result against warning and critical thresholds, like
+"queue.depth warn:>1000 crit:>5000".
+
+Response time thresholds can be set for the total response time, and for
+individual request phases: name resolution (dns), TCP connection (connect), TLS
+handshake (tls), and waiting for the first byte after connecting (ttfb). Empty
+thresholds are not checked. When redirects are followed, timings describe the
+final request only. Phases skipped on reused connections are measured as 0.
 `,
 	)
 	flags := cmd.Flags()
 	flags.StringVarP(&config.URL, "url", "u", "http://127.0.0.1:80/", "Target URL")
 	flags.StringVarP(&config.Timeout, "timeout", "t", "5s", "Connection timeout")
 	flags.StringSliceVarP(&config.Headers, "header", "H", []string{}, "HTTP header")
-	flags.BoolVarP(&config.Insecure, "insecure", "k", false, "Enable insecure connections")
-	flags.StringVarP(&config.Certfile, "cert", "c", "", "Certificate file")
-	flags.StringVarP(&config.CAfile, "ca", "C", "", "CA Certificate file")
-	flags.StringVarP(&config.Expiry, "expiry", "e", "", "Warn EXPIRY before cert expires (duration, like 5d)")
+	config.tlsClient.SetFlags(flags)
+	config.inspector.SetFlags(flags)
+	config.ocsp.SetFlags(flags)
+	config.expiry.SetFlags(flags)
 	flags.StringVarP(&config.Method, "method", "X", "GET", "HTTP method")
 	flags.BoolVar(&config.Metrics, "metrics", false, "Output measurements in OpenTSDB format")
 	flags.StringVarP(&config.UserAgent, "user-agent", "A", "", "User agent")
 	flags.StringVarP(&config.Data, "body", "d", "", "HTTP body")
 	flags.StringVarP(&config.JSONkey, "json-key", "K", "", "JSON key selector in JMESPath syntax")
 	flags.StringVarP(&config.JSONval, "json-val", "V", "", "expected value for JSON key in string form")
+	flags.StringArrayVarP(&config.JSONAsserts, "json-assert", "J", nil, "JSON assertion in [warn:|crit:]EXPR OP VALUE"+
+		" or EXPR [warn:OPNUM] [crit:OPNUM] form (repeatable)")
+	flags.StringVar(&config.JSONSchema, "json-schema", "", "Validate JSON response body against JSON Schema file")
+	flags.UintVar(&config.SchemaErrors, "schema-errors", 3, "Show at most COUNT JSON Schema violations; 0 shows all")
 	flags.UintVarP(&config.Response, "response", "r", 2, "HTTP error code to expect; use 3-digits for exact, "+
 		"1-digit for first digit check")
 	flags.StringVarP(&config.Redirect, "redirect", "R", "", "Expect redirection to")
+	flags.IntVarP(&config.Follow, "follow", "L", 0, "Follow at most COUNT redirects")
+	flags.StringVar(&config.FinalURL, "final-url", "", "Expect final URL after following redirects")
+	flags.StringArrayVar(&config.ExpectHeader, "expect-header", nil, "Critical if response header assertion in"+
+		" NAME, !NAME, NAME=VALUE, or NAME~=REGEX form fails (repeatable)")
+	flags.BoolVar(&config.Audit, "audit", false, "Warn on missing security headers and insecure cookies")
+	flags.StringVar(&config.HSTSMinAge, "hsts-min-age", "180d", "Minimum Strict-Transport-Security max-age for --audit")
+	config.body.SetFlags(flags)
+	flags.StringVar(&config.MaxBytes, "max-bytes", "", "Read at most SIZE of response body")
+
+	for _, latency := range config.latencies {
+		flags.StringVar(&latency.warnS, latency.name+"-warn", "", "Warn if "+latency.desc+" takes DURATION or longer")
+		flags.StringVar(&latency.critS, latency.name+"-crit", "", "Critical if "+latency.desc+" takes DURATION or longer")
+	}
 
 	return cmd
 }
@@ -91,13 +169,70 @@ func (conf *httpConfig) check() error {
 		return fmt.Errorf("cannot parse --timeout: %w", err)
 	}
 
-	if len(conf.Expiry) != 0 {
-		var err error
+	if err := conf.tlsClient.Check(); err != nil {
+		return err
+	}
+
+	if err := conf.inspector.Check(); err != nil {
+		return err
+	}
+
+	if err := conf.ocsp.Check(); err != nil {
+		return err
+	}
+
+	if err := conf.expiry.Check(); err != nil {
+		return err
+	}
+
+	for _, latency := range conf.latencies {
+		if err := latency.check(); err != nil {
+			return err
+		}
+	}
+
+	if err := conf.body.Check(); err != nil {
+		return err
+	}
 
-		//conf.expiry, err = time.ParseDuration(conf.Expiry)
-		conf.expiry, err = tparse.ParseNow(time.RFC3339, "now+"+conf.Expiry)
+	conf.headers = make([]*measurements.HeaderAssertion, len(conf.ExpectHeader))
+
+	for i, item := range conf.ExpectHeader {
+		conf.headers[i], err = measurements.ParseHeaderAssertion(item)
 		if err != nil {
-			return fmt.Errorf("cannot parse --expiry: %w", err)
+			return fmt.Errorf("cannot interpret --expect-header %q: %w", item, err)
+		}
+	}
+
+	if conf.Audit {
+		minAge, err := tparse.ParseNow(time.RFC3339, "now+"+conf.HSTSMinAge)
+		if err != nil {
+			return fmt.Errorf("cannot parse --hsts-min-age: %w", err)
+		}
+
+		conf.hstsMinAge = time.Until(minAge).Round(time.Second)
+	}
+
+	conf.jsonAsserts = make([]*measurements.JSONAssertion, len(conf.JSONAsserts))
+
+	for i, item := range conf.JSONAsserts {
+		conf.jsonAsserts[i], err = measurements.ParseJSONAssertion(item)
+		if err != nil {
+			return fmt.Errorf("cannot interpret --json-assert %q: %w", item, err)
+		}
+	}
+
+	if len(conf.JSONSchema) != 0 {
+		conf.jsonSchema, err = jsonschema.Compile(conf.JSONSchema)
+		if err != nil {
+			return fmt.Errorf("cannot load --json-schema: %w", err)
+		}
+	}
+
+	if len(conf.MaxBytes) != 0 {
+		conf.body.MaxBytes, err = parseSize(conf.MaxBytes)
+		if err != nil {
+			return fmt.Errorf("parsing --max-bytes: %w", err)
 		}
 	}
 
@@ -119,6 +254,9 @@ func (conf *httpConfig) check() error {
 				!(conf.Response == 3 || (conf.Response >= 300 && conf.Response < 400)),
 			"should expect 3xx if redirect is also expected",
 		},
+		{"follow", conf.Follow < 0, "should not be negative"},
+		{"follow", conf.Follow > 0 && len(conf.Redirect) != 0, "cannot be used with --redirect; use --final-url"},
+		{"final-url", len(conf.FinalURL) != 0 && conf.Follow == 0, "requires --follow"},
 	}
 	for _, test := range tests {
 		if test.check {
@@ -126,6 +264,8 @@ func (conf *httpConfig) check() error {
 		}
 	}
 
+	conf.redirects.Max = conf.Follow
+
 	return nil
 }
 
@@ -155,14 +295,14 @@ func (conf *httpConfig) Run(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	if conf.Metrics {
+	if conf.Metrics || conf.hasLatencies() {
 		conf.tracer = measurements.NewHTTPTracer()
 		req = req.WithContext(httptrace.WithClientTrace(context.Background(), conf.tracer.Trace))
 	}
 
 	resp, err := client.Do(req)
 	if err != nil {
-		return sensulib.Crit(err)
+		return conf.checkTLS(sensulib.Crit(err))
 	}
 
 	defer resp.Body.Close()
@@ -171,16 +311,27 @@ func (conf *httpConfig) Run(cmd *cobra.Command, args []string) error {
 		return conf.printMetrics(req, resp)
 	}
 
-	if len(conf.Expiry) != 0 {
-		for _, cert := range conf.certList {
-			if cert.NotAfter.Before(conf.expiry) {
-				return sensulib.Warn(fmt.Errorf(
-					"certificate %s will expire in %s",
-					cert.Subject,
-					durafmt.Parse(time.Until(cert.NotAfter)).LimitFirstN(4).String(),
-				))
-			}
+	if err := conf.checkTLS(nil); err != nil {
+		return err
+	}
+
+	if err := conf.checkOCSP(); err != nil {
+		return err
+	}
+
+	if err := conf.expiry.Verify(conf.certList); err != nil {
+		return err
+	}
+
+	var body []byte
+
+	if conf.needBody() {
+		body, err = conf.body.Read(resp.Body)
+		if err != nil {
+			return sensulib.Crit(fmt.Errorf("cannot read response body: %w", err))
 		}
+
+		conf.tracer.Done()
 	}
 
 	if err := conf.checkResponse(resp); err != nil {
@@ -191,26 +342,156 @@ func (conf *httpConfig) Run(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	if conf.JSONkey != "" {
-		body, err := ioutil.ReadAll(resp.Body)
+	if err := conf.checkFinalURL(resp); err != nil {
+		return err
+	}
+
+	if err := conf.checkHeaders(resp); err != nil {
+		return err
+	}
+
+	if err := conf.checkLatencies(); err != nil {
+		return err
+	}
+
+	if err := conf.body.CheckContent(body); err != nil {
+		return err
+	}
+
+	if conf.needJSON() {
+		return conf.checkJSONContent(body)
+	}
+
+	if conf.redirects.Redirects() > 0 {
+		return sensulib.Ok(fmt.Errorf(
+			"HTTP request responded successfully with %s after %d redirects (%s)%s",
+			resp.Status,
+			conf.redirects.Redirects(),
+			&conf.redirects,
+			conf.tlsSummary(),
+		))
+	}
+
+	return sensulib.Ok(fmt.Errorf("HTTP request responded successfully with %s%s", resp.Status, conf.tlsSummary()))
+}
+
+// tlsSummary returns negotiated TLS parameters and certificate chain of the
+// inspected connection
+func (conf *httpConfig) tlsSummary() string {
+	if !conf.inspector.Enabled() || conf.tlsState == nil {
+		return ""
+	}
+
+	return fmt.Sprintf(
+		"\n%s, %s\n%s",
+		measurements.TLSVersionName(conf.tlsState.Version),
+		tls.CipherSuiteName(conf.tlsState.CipherSuite),
+		strings.Join(measurements.ChainSummary(conf.tlsState.PeerCertificates), "\n"),
+	)
+}
+
+func (conf *httpConfig) checkHeaders(resp *http.Response) error {
+	for _, assertion := range conf.headers {
+		if err := assertion.Check(resp.Header); err != nil {
+			return sensulib.Crit(err)
+		}
+	}
+
+	if !conf.Audit {
+		return nil
+	}
+
+	if issues := measurements.AuditSecurityHeaders(resp, conf.hstsMinAge); len(issues) > 0 {
+		return sensulib.Warn(fmt.Errorf("security header issues: %s", strings.Join(issues, "; ")))
+	}
+
+	return nil
+}
+
+func (latency *httpLatency) check() error {
+	var err error
+
+	for _, item := range []struct {
+		suffix string
+		source string
+		target *time.Duration
+	}{
+		{"warn", latency.warnS, &latency.warn},
+		{"crit", latency.critS, &latency.crit},
+	} {
+		if len(item.source) == 0 {
+			continue
+		}
+
+		*item.target, err = time.ParseDuration(item.source)
 		if err != nil {
-			return err
+			return fmt.Errorf("cannot parse --%s-%s: %w", latency.name, item.suffix, err)
 		}
+	}
 
-		return conf.checkJSONContent(body)
+	if latency.warn > 0 && latency.crit > 0 && latency.crit <= latency.warn {
+		return fmt.Errorf("--%s-crit should be higher than --%s-warn", latency.name, latency.name)
+	}
+
+	return nil
+}
+
+func (conf *httpConfig) hasLatencies() bool {
+	for _, latency := range conf.latencies {
+		if latency.warn > 0 || latency.crit > 0 {
+			return true
+		}
 	}
 
-	return sensulib.Ok(fmt.Errorf("HTTP request responded successfully with %s", resp.Status))
+	return false
+}
+
+func (conf *httpConfig) checkLatencies() error {
+	var crit, warn []string
+
+	for _, latency := range conf.latencies {
+		if latency.warn == 0 && latency.crit == 0 {
+			continue
+		}
+
+		measured := latency.measure(conf.tracer)
+		msg := fmt.Sprintf("%s took %s", latency.desc, measured.Round(time.Millisecond))
+
+		switch {
+		case latency.crit > 0 && measured >= latency.crit:
+			crit = append(crit, msg)
+		case latency.warn > 0 && measured >= latency.warn:
+			warn = append(warn, msg)
+		}
+	}
+
+	switch {
+	case len(crit) > 0:
+		return sensulib.Crit(fmt.Errorf("slow HTTP response: %s", strings.Join(append(crit, warn...), ", ")))
+	case len(warn) > 0:
+		return sensulib.Warn(fmt.Errorf("slow HTTP response: %s", strings.Join(warn, ", ")))
+	}
+
+	return nil
 }
 
 func (conf *httpConfig) httpClient() (*http.Client, error) {
-	tlsconfig := &tls.Config{}
+	tlsconfig, err := conf.tlsClient.Config()
+	if err != nil {
+		return nil, sensulib.Unknown(err)
+	}
+
+	verify := !tlsconfig.InsecureSkipVerify
+	roots := tlsconfig.RootCAs
+	conf.ocsp.Roots = roots
 
-	if conf.Insecure {
+	if conf.inspector.Enabled() {
+		// verification is done by the inspector to report problems with
+		// their own alert levels
 		tlsconfig.InsecureSkipVerify = true
 	}
 
-	if len(conf.Expiry) != 0 {
+	if conf.expiry.Enabled() || conf.inspector.Enabled() || conf.ocsp.Enabled() {
 		tlsconfig.VerifyConnection = func(cs tls.ConnectionState) error {
 			conf.certList = make([]*x509.Certificate, len(cs.PeerCertificates))
 
@@ -218,40 +499,92 @@ func (conf *httpConfig) httpClient() (*http.Client, error) {
 				conf.certList[idx] = cert
 			}
 
-			return nil
-		}
-	}
+			conf.tlsState = &cs
 
-	if len(conf.Certfile) != 0 {
-		cert, err := tls.LoadX509KeyPair(conf.Certfile, conf.Certfile)
-		if err != nil {
-			return nil, sensulib.Unknown(fmt.Errorf("cannot load --certfile: %w", err))
-		}
+			if !conf.inspector.Enabled() {
+				return nil
+			}
 
-		tlsconfig.Certificates = []tls.Certificate{cert}
-	}
+			// redirects can lead to other hosts with their own problems,
+			// which are all reported
+			fatal := false
+			current := conf.currentURL()
 
-	if len(conf.CAfile) != 0 {
-		cacontents, err := ioutil.ReadFile(conf.CAfile)
-		if err != nil {
-			return nil, sensulib.Unknown(fmt.Errorf("cannot load --ca: %w", err))
-		}
+			for _, problem := range conf.inspector.Inspect(cs, current.Hostname(), roots, verify) {
+				if conf.Follow > 0 {
+					problem.Message = fmt.Sprintf("%s: %s", current.Host, problem.Message)
+				}
+
+				conf.tlsProblems = append(conf.tlsProblems, problem)
+				fatal = fatal || conf.inspector.Fatal(problem)
+			}
 
-		certpool := x509.NewCertPool()
-		certpool.AppendCertsFromPEM(cacontents)
+			if fatal {
+				return errors.New("TLS inspection failed")
+			}
+
+			return nil
+		}
 	}
 
 	return &http.Client{
-		CheckRedirect: func(req *http.Request, via []*http.Request) error {
-			return http.ErrUseLastResponse
-		},
-		Timeout: conf.timeout,
+		CheckRedirect: conf.redirects.CheckRedirect,
+		Timeout:       conf.timeout,
 		Transport: &http.Transport{
 			TLSClientConfig: tlsconfig,
 		},
 	}, nil
 }
 
+// currentURL returns URL of the request being sent: the last redirect target,
+// or the requested URL
+func (conf *httpConfig) currentURL() *url.URL {
+	target := conf.URL
+	if len(conf.redirects.Chain) > 0 {
+		target = conf.redirects.Chain[len(conf.redirects.Chain)-1]
+	}
+
+	parsed, err := url.Parse(target)
+	if err != nil {
+		return &url.URL{Host: target}
+	}
+
+	return parsed
+}
+
+// checkTLS returns TLS inspection problems, or def if there are none
+func (conf *httpConfig) checkTLS(def *sensulib.Error) error {
+	errs := sensulib.NewErrors()
+
+	for _, err := range conf.inspector.Errors(conf.tlsProblems) {
+		errs.Add(err)
+	}
+
+	if def == nil {
+		return errs.Return(nil)
+	}
+
+	return errs.Return(def)
+}
+
+func (conf *httpConfig) checkOCSP() error {
+	if !conf.ocsp.Enabled() {
+		return nil
+	}
+
+	if conf.tlsState == nil {
+		return sensulib.Unknown(errors.New("OCSP check requires HTTPS connection"))
+	}
+
+	conf.ocsp.Client = &http.Client{Timeout: conf.timeout}
+
+	if err := conf.ocsp.Verify(*conf.tlsState); err != nil {
+		return err
+	}
+
+	return nil
+}
+
 func (conf *httpConfig) checkResponse(resp *http.Response) error {
 	// response
 	sfx := ""
@@ -280,6 +613,18 @@ func (conf *httpConfig) checkResponse(resp *http.Response) error {
 	return nil
 }
 
+func (conf *httpConfig) checkFinalURL(resp *http.Response) error {
+	if len(conf.FinalURL) == 0 {
+		return nil
+	}
+
+	if final := resp.Request.URL.String(); final != conf.FinalURL {
+		return sensulib.Crit(fmt.Errorf("final URL is %s, expected %s", final, conf.FinalURL))
+	}
+
+	return nil
+}
+
 func (conf *httpConfig) checkRedirect(resp *http.Response) error {
 	redirect := resp.Header.Get("Location")
 
@@ -303,39 +648,94 @@ func (conf *httpConfig) checkRedirect(resp *http.Response) error {
 	return nil
 }
 
+func (conf *httpConfig) needBody() bool {
+	return conf.needJSON() || conf.hasLatencies() || conf.body.Enabled()
+}
+
+func (conf *httpConfig) needJSON() bool {
+	return conf.JSONkey != "" || len(conf.jsonAsserts) > 0 || conf.jsonSchema != nil
+}
+
 func (conf *httpConfig) checkJSONContent(body []byte) error {
-	raw, err := conf.checkJSONKey(body)
-	if err != nil {
-		return sensulib.Crit(err)
+	var buf interface{}
+
+	if err := json.Unmarshal(body, &buf); err != nil {
+		return sensulib.Crit(fmt.Errorf("parsing response body as JSON: %w%s", err, conf.body.Snippet(body)))
 	}
 
-	if conf.JSONval != "" {
-		str := fmt.Sprintf("%v", raw)
-		if str != conf.JSONval {
-			return sensulib.Crit(fmt.Errorf("key %q has %q, wants %q", conf.JSONkey, str, conf.JSONval))
+	errs := sensulib.NewErrors()
+
+	errs.Add(conf.checkJSONSchema(buf))
+
+	for _, assertion := range conf.jsonAsserts {
+		errs.Add(assertion.Check(buf))
+	}
+
+	if conf.JSONkey == "" {
+		if len(conf.jsonAsserts) == 0 {
+			return errs.Return(sensulib.Ok(errors.New("HTTP request responded with valid JSON")))
 		}
 
-		err = fmt.Errorf("HTTP request responded with JSON key %q = %q", conf.JSONkey, conf.JSONval)
-	} else {
-		err = fmt.Errorf("HTTP request responded with existing JSON key %q", conf.JSONkey)
+		return errs.Return(sensulib.Ok(fmt.Errorf(
+			"HTTP request responded with %d JSON assertions met",
+			len(conf.jsonAsserts),
+		)))
+	}
+
+	errs.Add(conf.checkJSONKey(buf, body))
+
+	if conf.JSONval != "" {
+		return errs.Return(sensulib.Ok(fmt.Errorf(
+			"HTTP request responded with JSON key %q = %q",
+			conf.JSONkey,
+			conf.JSONval,
+		)))
 	}
 
-	return sensulib.Ok(err)
+	return errs.Return(sensulib.Ok(fmt.Errorf("HTTP request responded with existing JSON key %q", conf.JSONkey)))
 }
 
-func (conf *httpConfig) checkJSONKey(body []byte) (interface{}, error) {
-	var buf interface{}
+func (conf *httpConfig) checkJSONSchema(buf interface{}) *sensulib.Error {
+	if conf.jsonSchema == nil {
+		return nil
+	}
 
-	if err := json.Unmarshal(body, &buf); err != nil {
-		return nil, fmt.Errorf("parsing response body as JSON: %w", err)
+	err := conf.jsonSchema.Validate(buf)
+	if err == nil {
+		return nil
+	}
+
+	var verr *jsonschema.ValidationError
+	if !errors.As(err, &verr) {
+		return sensulib.Crit(fmt.Errorf("validating response body: %w", err))
 	}
 
+	return sensulib.Crit(fmt.Errorf(
+		"response body doesn't validate against JSON Schema: %s",
+		measurements.SummarizeViolations(measurements.SchemaViolations(verr), conf.SchemaErrors),
+	))
+}
+
+func (conf *httpConfig) checkJSONKey(buf interface{}, body []byte) *sensulib.Error {
 	raw, err := jmespath.Search(conf.JSONkey, buf)
 	if err != nil {
-		return nil, fmt.Errorf("searching for %q in body JSON: %w", conf.JSONkey, err)
+		return sensulib.Crit(fmt.Errorf("searching for %q in body JSON: %w", conf.JSONkey, err))
 	}
 
-	return raw, nil
+	if conf.JSONval != "" {
+		str := fmt.Sprintf("%v", raw)
+		if str != conf.JSONval {
+			return sensulib.Crit(fmt.Errorf(
+				"key %q has %q, wants %q%s",
+				conf.JSONkey,
+				str,
+				conf.JSONval,
+				conf.body.Snippet(body),
+			))
+		}
+	}
+
+	return nil
 }
 
 func (conf *httpConfig) printMetrics(req *http.Request, resp *http.Response) error {
@@ -357,6 +757,15 @@ func (conf *httpConfig) printMetrics(req *http.Request, resp *http.Response) err
 	log.Log("time.starttransfer", conf.tracer.Starttransfer().Microseconds())
 	log.Log("time.body_transfer", transfer_time.Microseconds())
 	log.Log("http.http_code", resp.StatusCode)
+
+	if conf.Follow > 0 {
+		log.Log("http.redirects", conf.redirects.Redirects())
+	}
+
+	if conf.inspector.Enabled() && conf.tlsState != nil {
+		conf.printTLSMetrics(log)
+	}
+
 	log.Log("http.body_bytes", written)
 	log.Log("http.error", err)
 
@@ -366,3 +775,14 @@ func (conf *httpConfig) printMetrics(req *http.Request, resp *http.Response) err
 
 	return nil
 }
+
+func (conf *httpConfig) printTLSMetrics(log *metrics.Metrics) {
+	for depth, cert := range conf.tlsState.PeerCertificates {
+		log.With(map[string]string{
+			"depth": fmt.Sprintf("%d", depth),
+			"cn":    cert.Subject.CommonName,
+		}).Log("tls.cert.days_left", measurements.DaysLeft(cert))
+	}
+
+	log.Log("tls.problems", len(conf.tlsProblems))
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/julian7/sensu-base-checks/metrics"
+	"github.com/julian7/sensulib"
+	"github.com/shirou/gopsutil/v3/cpu"
+	"github.com/shirou/gopsutil/v3/load"
+	"github.com/spf13/cobra"
+)
+
+type loadConfig struct {
+	Warn    []float64
+	Crit    []float64
+	Metrics bool
+}
+
+func loadCmd() *cobra.Command {
+	config := &loadConfig{}
+	cmd := sensulib.NewCommand(
+		config,
+		"load",
+		"Load average check",
+		`Checks for load averages, normalized per CPU core
+
+Load averages of the last 1, 5, and 15 minutes are divided by the number of
+logical CPU cores, and compared to warning and critical levels, provided in
+the same order.
+`,
+	)
+	flags := cmd.Flags()
+	flags.Float64SliceVarP(&config.Warn, "warn", "w", []float64{2.75, 2.5, 2.0}, "Warn if load average per core "+
+		"is at least these levels; 1, 5, and 15 minutes")
+	flags.Float64SliceVarP(&config.Crit, "crit", "c", []float64{3.5, 3.25, 3.0}, "Critical if load average per core "+
+		"is at least these levels; 1, 5, and 15 minutes")
+	flags.BoolVar(&config.Metrics, "metrics", false, "Output measurements in OpenTSDB format")
+
+	return cmd
+}
+
+func (conf *loadConfig) check() error {
+	if conf.Metrics {
+		return nil
+	}
+
+	for _, item := range []struct {
+		name        string
+		requirement bool
+	}{
+		{"--warn should have 3 levels", len(conf.Warn) == 3},
+		{"--crit should have 3 levels", len(conf.Crit) == 3},
+	} {
+		if !item.requirement {
+			return errors.New(item.name)
+		}
+	}
+
+	for i := range conf.Warn {
+		if conf.Warn[i] <= 0 || conf.Crit[i] <= conf.Warn[i] {
+			return errors.New("--crit levels should be higher than --warn levels, which should be higher than 0")
+		}
+	}
+
+	return nil
+}
+
+func (conf *loadConfig) Run(cmd *cobra.Command, args []string) error {
+	if err := conf.check(); err != nil {
+		return sensulib.Unknown(err)
+	}
+
+	avg, err := load.Avg()
+	if err != nil {
+		return sensulib.Unknown(fmt.Errorf("cannot read load average: %w", err))
+	}
+
+	cores, err := cpu.Counts(true)
+	if err != nil {
+		return sensulib.Unknown(fmt.Errorf("cannot count CPU cores: %w", err))
+	}
+
+	if cores < 1 {
+		return sensulib.Unknown(errors.New("cannot count CPU cores"))
+	}
+
+	loads := []float64{avg.Load1, avg.Load5, avg.Load15}
+	perCore := make([]float64, len(loads))
+
+	for i, load := range loads {
+		perCore[i] = load / float64(cores)
+	}
+
+	if conf.Metrics {
+		log := metrics.New("load")
+
+		log.Log("cores", cores)
+
+		for i, name := range []string{"load1", "load5", "load15"} {
+			log.Log(name, loads[i])
+			log.Log(name+"_per_core", perCore[i])
+		}
+
+		return nil
+	}
+
+	err = fmt.Errorf(
+		"load average per core is %.2f, %.2f, %.2f (%d cores)",
+		perCore[0],
+		perCore[1],
+		perCore[2],
+		cores,
+	)
+
+	for i := range perCore {
+		if perCore[i] >= conf.Crit[i] {
+			return sensulib.Crit(err)
+		}
+	}
+
+	for i := range perCore {
+		if perCore[i] >= conf.Warn[i] {
+			return sensulib.Warn(err)
+		}
+	}
+
+	return sensulib.Ok(err)
+}
@@ -15,7 +15,22 @@ func rootCmd() *cobra.Command {
 any nagios-style monitoring solutions too.`,
 		Version: version,
 	}
-	app.AddCommand(filesystemCmd(), httpCmd(), timeCmd())
+	app.AddCommand(
+		allocationCmd(),
+		cpuCmd(),
+		dirsizeCmd(),
+		diskioCmd(),
+		fileCmd(),
+		filesystemCmd(),
+		httpCmd(),
+		memoryCmd(),
+		processCmd(),
+		psiCmd(),
+		tcpCmd(),
+		timeCmd(),
+		tlsCmd(),
+		zfsCmd(),
+	)
 
 	return app
 }
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/julian7/sensu-base-checks/measurements"
+	"github.com/julian7/sensu-base-checks/metrics"
+	"github.com/julian7/sensulib"
+	"github.com/shirou/gopsutil/v3/mem"
+	"github.com/spf13/cobra"
+)
+
+type memoryConfig struct {
+	WarnBytesS string
+	CritBytesS string
+	State      string
+	RWarnS     string
+	RCritS     string
+	Metrics    bool
+	thresholds measurements.MemoryThresholds
+}
+
+func memoryCmd() *cobra.Command {
+	config := &memoryConfig{}
+	cmd := sensulib.NewCommand(
+		config,
+		"memory",
+		"Memory and swap check",
+		`Checks for available memory, swap usage, and swap activity
+
+Swap-in and swap-out rates are calculated by comparing counters with the ones
+saved by the previous run in the state file, therefore they are available only
+if --state is provided, from the second run on.
+
+Sizes can be provided with binary units (eg. 512k, 10M, 1.5GiB). Zero or empty
+thresholds are not checked.
+`,
+	)
+	flags := cmd.Flags()
+	flags.Float64VarP(&config.thresholds.Warn, "warn", "w", 10.0, "Warn if PERCENT or less of memory is available; [0,100)")
+	flags.Float64VarP(&config.thresholds.Crit, "crit", "c", 5.0, "Critical if PERCENT or less of memory is available; [0,100)")
+	flags.StringVar(&config.WarnBytesS, "warn-bytes", "", "Warn if SIZE or less memory is available")
+	flags.StringVar(&config.CritBytesS, "crit-bytes", "", "Critical if SIZE or less memory is available")
+	flags.Float64VarP(&config.thresholds.SWarn, "swarn", "W", 0, "Warn if PERCENT or more of swap is used; [0,100]")
+	flags.Float64VarP(&config.thresholds.SCrit, "scrit", "C", 0, "Critical if PERCENT or more of swap is used; [0,100]")
+	flags.StringVarP(&config.State, "state", "s", "", "State file to calculate swap rates since previous run")
+	flags.StringVar(&config.RWarnS, "rwarn", "", "Warn if swap-in or swap-out rate is at least SIZE per second")
+	flags.StringVar(&config.RCritS, "rcrit", "", "Critical if swap-in or swap-out rate is at least SIZE per second")
+	flags.BoolVar(&config.Metrics, "metrics", false, "Output measurements in OpenTSDB format")
+
+	return cmd
+}
+
+func (conf *memoryConfig) check() error {
+	var err error
+
+	th := &conf.thresholds
+
+	for _, item := range []struct {
+		name   string
+		source string
+		target *uint64
+	}{
+		{"warn-bytes", conf.WarnBytesS, &th.WarnBytes},
+		{"crit-bytes", conf.CritBytesS, &th.CritBytes},
+		{"rwarn", conf.RWarnS, &th.RWarn},
+		{"rcrit", conf.RCritS, &th.RCrit},
+	} {
+		if len(item.source) == 0 {
+			continue
+		}
+
+		*item.target, err = parseSize(item.source)
+		if err != nil {
+			return fmt.Errorf("parsing --%s: %w", item.name, err)
+		}
+	}
+
+	if conf.Metrics {
+		return nil
+	}
+
+	for _, item := range []struct {
+		name        string
+		requirement bool
+	}{
+		{"--warn should be between 0 and 100", th.Warn >= 0 && th.Warn < 100},
+		{"--crit should be between 0 and 100", th.Crit >= 0 && th.Crit < 100},
+		{"--crit should be lower than --warn", th.Warn == 0 || th.Crit < th.Warn},
+		{"--crit-bytes should be lower than --warn-bytes", th.WarnBytes == 0 || th.CritBytes < th.WarnBytes},
+		{"--swarn should be between 0 and 100", th.SWarn >= 0 && th.SWarn <= 100},
+		{"--scrit should be between 0 and 100", th.SCrit >= 0 && th.SCrit <= 100},
+		{"--scrit should be higher than --swarn", th.SWarn == 0 || th.SCrit == 0 || th.SCrit > th.SWarn},
+		{"--rcrit should be higher than --rwarn", th.RWarn == 0 || th.RCrit == 0 || th.RCrit > th.RWarn},
+		{"--state should be set for swap rate checks", len(conf.State) > 0 || (th.RWarn == 0 && th.RCrit == 0)},
+	} {
+		if !item.requirement {
+			return errors.New(item.name)
+		}
+	}
+
+	return nil
+}
+
+func (conf *memoryConfig) Run(cmd *cobra.Command, args []string) error {
+	if err := conf.check(); err != nil {
+		return sensulib.Unknown(err)
+	}
+
+	vmem, err := mem.VirtualMemory()
+	if err != nil {
+		return sensulib.Unknown(fmt.Errorf("cannot read memory statistics: %w", err))
+	}
+
+	swap, err := mem.SwapMemory()
+	if err != nil {
+		return sensulib.Unknown(fmt.Errorf("cannot read swap statistics: %w", err))
+	}
+
+	var rates *measurements.SwapRates
+
+	if len(conf.State) > 0 {
+		rates, err = measurements.SwapRatesSince(conf.State, time.Now(), swap)
+		if err != nil {
+			return sensulib.Unknown(err)
+		}
+	}
+
+	if conf.Metrics {
+		conf.printMetrics(vmem, swap, rates)
+		return nil
+	}
+
+	errs := sensulib.NewErrors()
+	errs.Add(conf.thresholds.CheckMemory(vmem))
+	errs.Add(conf.thresholds.CheckSwap(swap))
+	errs.Add(conf.thresholds.CheckSwapRates(rates))
+
+	return errs.Return(sensulib.Ok(fmt.Errorf(
+		"%s memory available (%s of %s)",
+		sensulib.PercentToHuman(measurements.AvailablePercent(vmem), 1),
+		sensulib.SizeToHuman(vmem.Available),
+		sensulib.SizeToHuman(vmem.Total),
+	)))
+}
+
+func (conf *memoryConfig) printMetrics(vmem *mem.VirtualMemoryStat, swap *mem.SwapMemoryStat, rates *measurements.SwapRates) {
+	log := metrics.New("memory")
+
+	log.Log("bytes.total", vmem.Total)
+	log.Log("bytes.available", vmem.Available)
+	log.Log("bytes.used", vmem.Used)
+	log.Log("bytes.free", vmem.Free)
+	log.Log("bytes.available_percent", measurements.AvailablePercent(vmem))
+	log.Log("swap.bytes.total", swap.Total)
+	log.Log("swap.bytes.used", swap.Used)
+	log.Log("swap.bytes.free", swap.Free)
+	log.Log("swap.bytes.used_percent", swap.UsedPercent)
+
+	if rates != nil {
+		log.Log("swap.speed.in", rates.In)
+		log.Log("swap.speed.out", rates.Out)
+	}
+}
@@ -0,0 +1,358 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/julian7/sensu-base-checks/measurements"
+	"github.com/julian7/sensu-base-checks/metrics"
+	"github.com/julian7/sensulib"
+	"github.com/shirou/gopsutil/v3/cpu"
+	"github.com/spf13/cobra"
+)
+
+type processConfig struct {
+	filter      measurements.ProcessFilter
+	WarnUnder   int
+	CritUnder   int
+	WarnOver    int
+	CritOver    int
+	Interval    string
+	interval    time.Duration
+	CPUWarn     float64
+	CPUCrit     float64
+	RSSWarnS    string
+	rssWarn     uint64
+	RSSCritS    string
+	rssCrit     uint64
+	FDWarn      int32
+	FDCrit      int32
+	ThreadsWarn int32
+	ThreadsCrit int32
+	Zombies     string
+	Metrics     bool
+}
+
+type processStats struct {
+	info       *measurements.ProcessInfo
+	cpuPercent float64
+	rss        uint64
+	fds        int32
+	threads    int32
+}
+
+func processCmd() *cobra.Command {
+	config := &processConfig{}
+	cmd := sensulib.NewCommand(
+		config,
+		"process",
+		"Process check",
+		`Checks for running processes
+
+Processes are selected by name, command line regular expression, user, or
+pidfile. When more criteria are provided, processes have to match all of them.
+The number of matching processes is checked, as well as per-process resource
+usage. Zombie processes are not counted as running.
+
+CPU usage is calculated over the sampling interval, and it can be higher than
+100% for multi-threaded processes. RSS can be provided with binary units (eg.
+512k, 10M, 1.5GiB). Zero or empty thresholds are not checked.
+`,
+	)
+	flags := cmd.Flags()
+	config.filter.SetFlags(flags)
+	flags.IntVarP(&config.WarnUnder, "warn-under", "W", 1, "Warn if less than COUNT processes are running")
+	flags.IntVarP(&config.CritUnder, "crit-under", "C", 1, "Critical if less than COUNT processes are running")
+	flags.IntVarP(&config.WarnOver, "warn-over", "w", 0, "Warn if more than COUNT processes are running")
+	flags.IntVarP(&config.CritOver, "crit-over", "c", 0, "Critical if more than COUNT processes are running")
+	flags.StringVarP(&config.Interval, "interval", "i", "1s", "CPU usage sampling interval")
+	flags.Float64Var(&config.CPUWarn, "cpu-warn", 0, "Warn if a process uses PERCENT or more CPU")
+	flags.Float64Var(&config.CPUCrit, "cpu-crit", 0, "Critical if a process uses PERCENT or more CPU")
+	flags.StringVar(&config.RSSWarnS, "rss-warn", "", "Warn if a process uses SIZE or more resident memory")
+	flags.StringVar(&config.RSSCritS, "rss-crit", "", "Critical if a process uses SIZE or more resident memory")
+	flags.Int32Var(&config.FDWarn, "fd-warn", 0, "Warn if a process has COUNT or more open files")
+	flags.Int32Var(&config.FDCrit, "fd-crit", 0, "Critical if a process has COUNT or more open files")
+	flags.Int32Var(&config.ThreadsWarn, "threads-warn", 0, "Warn if a process has COUNT or more threads")
+	flags.Int32Var(&config.ThreadsCrit, "threads-crit", 0, "Critical if a process has COUNT or more threads")
+	flags.StringVarP(&config.Zombies, "zombies", "z", "warn", "Alert level of matching zombie processes:"+
+		" ignore, warn, or crit")
+	flags.BoolVar(&config.Metrics, "metrics", false, "Output measurements in OpenTSDB format")
+
+	return cmd
+}
+
+func (conf *processConfig) check() error {
+	var err error
+
+	if err := conf.filter.Check(); err != nil {
+		return err
+	}
+
+	conf.interval, err = time.ParseDuration(conf.Interval)
+	if err != nil {
+		return fmt.Errorf("cannot parse --interval: %w", err)
+	}
+
+	for _, item := range []struct {
+		name   string
+		source string
+		target *uint64
+	}{
+		{"rss-warn", conf.RSSWarnS, &conf.rssWarn},
+		{"rss-crit", conf.RSSCritS, &conf.rssCrit},
+	} {
+		if len(item.source) == 0 {
+			continue
+		}
+
+		*item.target, err = parseSize(item.source)
+		if err != nil {
+			return fmt.Errorf("parsing --%s: %w", item.name, err)
+		}
+	}
+
+	if conf.Metrics {
+		return nil
+	}
+
+	for _, item := range []struct {
+		name        string
+		requirement bool
+	}{
+		{"--interval should be set for CPU checks", conf.interval > 0 || (conf.CPUWarn == 0 && conf.CPUCrit == 0)},
+		{"--warn-under and --crit-under should not be negative", conf.WarnUnder >= 0 && conf.CritUnder >= 0},
+		{"--crit-under should not be higher than --warn-under", conf.CritUnder <= conf.WarnUnder},
+		{"--warn-over and --crit-over should not be negative", conf.WarnOver >= 0 && conf.CritOver >= 0},
+		{
+			"--crit-over should not be lower than --warn-over",
+			conf.WarnOver == 0 || conf.CritOver == 0 || conf.CritOver >= conf.WarnOver,
+		},
+		{
+			"--cpu-crit should be higher than --cpu-warn",
+			conf.CPUWarn == 0 || conf.CPUCrit == 0 || conf.CPUCrit > conf.CPUWarn,
+		},
+		{
+			"--rss-crit should be higher than --rss-warn",
+			conf.rssWarn == 0 || conf.rssCrit == 0 || conf.rssCrit > conf.rssWarn,
+		},
+		{"--fd-crit should be higher than --fd-warn", conf.FDWarn == 0 || conf.FDCrit == 0 || conf.FDCrit > conf.FDWarn},
+		{
+			"--threads-crit should be higher than --threads-warn",
+			conf.ThreadsWarn == 0 || conf.ThreadsCrit == 0 || conf.ThreadsCrit > conf.ThreadsWarn,
+		},
+		{
+			"--zombies should be ignore, warn, or crit",
+			conf.Zombies == "ignore" || conf.Zombies == "warn" || conf.Zombies == "crit",
+		},
+	} {
+		if !item.requirement {
+			return errors.New(item.name)
+		}
+	}
+
+	return nil
+}
+
+func (conf *processConfig) Run(cmd *cobra.Command, args []string) error {
+	if err := conf.check(); err != nil {
+		return sensulib.Unknown(err)
+	}
+
+	procs, err := conf.filter.Processes()
+	if err != nil {
+		return sensulib.Unknown(err)
+	}
+
+	var running, zombies []*measurements.ProcessInfo
+
+	for _, proc := range procs {
+		if proc.Zombie {
+			zombies = append(zombies, proc)
+		} else {
+			running = append(running, proc)
+		}
+	}
+
+	stats := conf.stats(running)
+
+	if conf.Metrics {
+		conf.printMetrics(len(running), len(zombies), stats)
+		return nil
+	}
+
+	errs := sensulib.NewErrors()
+
+	errs.Add(conf.checkCount(len(running)))
+	errs.Add(conf.checkZombies(zombies))
+
+	for _, stat := range stats {
+		errs.Add(conf.checkStats(stat))
+	}
+
+	return errs.Return(sensulib.Ok(fmt.Errorf(
+		"%d processes are running matching %s",
+		len(running),
+		conf.filter.String(),
+	)))
+}
+
+func (conf *processConfig) needCPU() bool {
+	return conf.Metrics || conf.CPUWarn > 0 || conf.CPUCrit > 0
+}
+
+// stats collects resource usage of processes. Processes exiting in the
+// meantime are left out.
+func (conf *processConfig) stats(procs []*measurements.ProcessInfo) []*processStats {
+	prev := map[int32]*cpu.TimesStat{}
+
+	if conf.needCPU() && conf.interval > 0 && len(procs) > 0 {
+		for _, info := range procs {
+			if times, err := info.Process.Times(); err == nil {
+				prev[info.PID] = times
+			}
+		}
+
+		time.Sleep(conf.interval)
+	}
+
+	stats := make([]*processStats, 0, len(procs))
+
+	for _, info := range procs {
+		stat := &processStats{info: info, fds: -1, threads: -1}
+
+		if running, err := info.Process.IsRunning(); err != nil || !running {
+			continue
+		}
+
+		if times, err := info.Process.Times(); err == nil && prev[info.PID] != nil {
+			used := times.User + times.System - prev[info.PID].User - prev[info.PID].System
+			stat.cpuPercent = used / conf.interval.Seconds() * 100
+		}
+
+		if meminfo, err := info.Process.MemoryInfo(); err == nil {
+			stat.rss = meminfo.RSS
+		}
+
+		if fds, err := info.Process.NumFDs(); err == nil {
+			stat.fds = fds
+		}
+
+		if threads, err := info.Process.NumThreads(); err == nil {
+			stat.threads = threads
+		}
+
+		stats = append(stats, stat)
+	}
+
+	return stats
+}
+
+func (conf *processConfig) checkCount(count int) *sensulib.Error {
+	switch {
+	case count < conf.CritUnder:
+		return sensulib.Crit(fmt.Errorf("%d processes are running, expected at least %d", count, conf.CritUnder))
+	case conf.CritOver > 0 && count > conf.CritOver:
+		return sensulib.Crit(fmt.Errorf("%d processes are running, expected at most %d", count, conf.CritOver))
+	case count < conf.WarnUnder:
+		return sensulib.Warn(fmt.Errorf("%d processes are running, expected at least %d", count, conf.WarnUnder))
+	case conf.WarnOver > 0 && count > conf.WarnOver:
+		return sensulib.Warn(fmt.Errorf("%d processes are running, expected at most %d", count, conf.WarnOver))
+	}
+
+	return nil
+}
+
+func (conf *processConfig) checkZombies(zombies []*measurements.ProcessInfo) *sensulib.Error {
+	if len(zombies) == 0 || conf.Zombies == "ignore" {
+		return nil
+	}
+
+	names := make([]string, len(zombies))
+	for i, info := range zombies {
+		names[i] = fmt.Sprintf("%s (%d)", info.Name, info.PID)
+	}
+
+	err := fmt.Errorf("zombie processes: %s", strings.Join(names, ", "))
+
+	if conf.Zombies == "crit" {
+		return sensulib.Crit(err)
+	}
+
+	return sensulib.Warn(err)
+}
+
+func (conf *processConfig) checkStats(stat *processStats) *sensulib.Error {
+	var crit, warn []string
+
+	for _, item := range []struct {
+		msg        string
+		value      float64
+		warn, crit float64
+	}{
+		{
+			fmt.Sprintf("%s CPU", sensulib.PercentToHuman(stat.cpuPercent, 1)),
+			stat.cpuPercent, conf.CPUWarn, conf.CPUCrit,
+		},
+		{
+			fmt.Sprintf("%s RSS", sensulib.SizeToHuman(stat.rss)),
+			float64(stat.rss), float64(conf.rssWarn), float64(conf.rssCrit),
+		},
+		{
+			fmt.Sprintf("%d open files", stat.fds),
+			float64(stat.fds), float64(conf.FDWarn), float64(conf.FDCrit),
+		},
+		{
+			fmt.Sprintf("%d threads", stat.threads),
+			float64(stat.threads), float64(conf.ThreadsWarn), float64(conf.ThreadsCrit),
+		},
+	} {
+		switch {
+		case item.crit > 0 && item.value >= item.crit:
+			crit = append(crit, item.msg)
+		case item.warn > 0 && item.value >= item.warn:
+			warn = append(warn, item.msg)
+		}
+	}
+
+	switch {
+	case len(crit) > 0:
+		return sensulib.Crit(fmt.Errorf(
+			"%s (%d) uses %s",
+			stat.info.Name,
+			stat.info.PID,
+			strings.Join(append(crit, warn...), ", "),
+		))
+	case len(warn) > 0:
+		return sensulib.Warn(fmt.Errorf("%s (%d) uses %s", stat.info.Name, stat.info.PID, strings.Join(warn, ", ")))
+	}
+
+	return nil
+}
+
+func (conf *processConfig) printMetrics(running, zombies int, stats []*processStats) {
+	log := metrics.New("process")
+
+	log.Log("count", running)
+	log.Log("zombies", zombies)
+
+	for _, stat := range stats {
+		proclog := log.With(map[string]string{
+			"pid":  strconv.Itoa(int(stat.info.PID)),
+			"name": stat.info.Name,
+		})
+
+		proclog.Log("cpu_percent", stat.cpuPercent)
+		proclog.Log("bytes.rss", stat.rss)
+
+		if stat.fds >= 0 {
+			proclog.Log("fds", stat.fds)
+		}
+
+		if stat.threads >= 0 {
+			proclog.Log("threads", stat.threads)
+		}
+	}
+}
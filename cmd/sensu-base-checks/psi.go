@@ -0,0 +1,202 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/julian7/sensu-base-checks/measurements"
+	"github.com/julian7/sensu-base-checks/metrics"
+	"github.com/julian7/sensulib"
+	"github.com/spf13/cobra"
+)
+
+var psiWindows = []string{"avg10", "avg60", "avg300"}
+
+type psiConfig struct {
+	Resources  []string
+	Cgroup     string
+	SomeWarn   []float64
+	SomeCrit   []float64
+	FullWarn   []float64
+	FullCrit   []float64
+	Metrics    bool
+	procRoot   string
+	cgroupRoot string
+}
+
+func psiCmd() *cobra.Command {
+	config := &psiConfig{
+		procRoot:   "/proc/pressure",
+		cgroupRoot: "/sys/fs/cgroup",
+	}
+	cmd := sensulib.NewCommand(
+		config,
+		"psi",
+		"Pressure stall information check",
+		`Checks for linux pressure stall information (PSI)
+
+PSI shows the percentage of time tasks were stalled on CPU, memory, or I/O
+("some" shows time when at least some tasks were stalled, "full" shows time
+when all non-idle tasks were stalled), averaged over 10s, 60s, and 300s.
+
+Levels are provided for avg10, avg60, and avg300 averages, in this order.
+Zero levels are not checked.
+`,
+	)
+	flags := cmd.Flags()
+	flags.StringSliceVarP(&config.Resources, "resource", "r", []string{"cpu", "memory", "io"}, "Resources to check")
+	flags.StringVarP(&config.Cgroup, "cgroup", "g", "", "Check cgroup v2 pressure files of this cgroup path,"+
+		" relative to /sys/fs/cgroup")
+	flags.Float64SliceVarP(&config.SomeWarn, "some-warn", "w", []float64{0, 50, 0}, "Warn if some tasks are stalled"+
+		" PERCENT or more of the time")
+	flags.Float64SliceVarP(&config.SomeCrit, "some-crit", "c", []float64{0, 80, 0}, "Critical if some tasks are"+
+		" stalled PERCENT or more of the time")
+	flags.Float64SliceVarP(&config.FullWarn, "full-warn", "W", []float64{0, 10, 0}, "Warn if all tasks are stalled"+
+		" PERCENT or more of the time")
+	flags.Float64SliceVarP(&config.FullCrit, "full-crit", "C", []float64{0, 30, 0}, "Critical if all tasks are"+
+		" stalled PERCENT or more of the time")
+	flags.BoolVar(&config.Metrics, "metrics", false, "Output measurements in OpenTSDB format")
+
+	return cmd
+}
+
+func (conf *psiConfig) check() error {
+	if len(conf.Resources) == 0 {
+		return errors.New("--resource should be set")
+	}
+
+	for _, resource := range conf.Resources {
+		switch resource {
+		case "cpu", "memory", "io":
+		default:
+			return fmt.Errorf("--resource should be cpu, memory, or io, not %q", resource)
+		}
+	}
+
+	if conf.Metrics {
+		return nil
+	}
+
+	for _, item := range []struct {
+		name   string
+		levels []float64
+	}{
+		{"some-warn", conf.SomeWarn},
+		{"some-crit", conf.SomeCrit},
+		{"full-warn", conf.FullWarn},
+		{"full-crit", conf.FullCrit},
+	} {
+		if len(item.levels) != len(psiWindows) {
+			return fmt.Errorf("--%s should have %d levels", item.name, len(psiWindows))
+		}
+
+		for _, level := range item.levels {
+			if level < 0 || level > 100 {
+				return fmt.Errorf("--%s levels should be between 0 and 100", item.name)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (conf *psiConfig) path(resource string) string {
+	if len(conf.Cgroup) > 0 {
+		return filepath.Join(conf.cgroupRoot, conf.Cgroup, resource+".pressure")
+	}
+
+	return filepath.Join(conf.procRoot, resource)
+}
+
+func (conf *psiConfig) Run(cmd *cobra.Command, args []string) error {
+	if err := conf.check(); err != nil {
+		return sensulib.Unknown(err)
+	}
+
+	var log *metrics.Metrics
+
+	if conf.Metrics {
+		log = metrics.New("psi")
+
+		if len(conf.Cgroup) > 0 {
+			log = log.With(map[string]string{"cgroup": conf.Cgroup})
+		}
+	}
+
+	errs := sensulib.NewErrors()
+
+	for _, resource := range conf.Resources {
+		pressure, err := measurements.ReadPressure(conf.path(resource))
+		if err != nil {
+			return sensulib.Unknown(fmt.Errorf("cannot read %s pressure: %w", resource, err))
+		}
+
+		if conf.Metrics {
+			conf.printMetrics(log.With(map[string]string{"resource": resource}), pressure)
+			continue
+		}
+
+		errs.Add(conf.checkPressure(resource, "some", pressure.Some, conf.SomeWarn, conf.SomeCrit))
+
+		if pressure.Full != nil {
+			errs.Add(conf.checkPressure(resource, "full", pressure.Full, conf.FullWarn, conf.FullCrit))
+		}
+	}
+
+	if conf.Metrics {
+		return nil
+	}
+
+	return errs.Return(sensulib.Ok(errors.New("no significant resource pressure")))
+}
+
+func (conf *psiConfig) checkPressure(
+	resource, kind string,
+	stat *measurements.PressureStat,
+	warn, crit []float64,
+) *sensulib.Error {
+	averages := stat.Averages()
+
+	for _, item := range []struct {
+		levels []float64
+		fn     func(error) *sensulib.Error
+	}{
+		{crit, sensulib.Crit},
+		{warn, sensulib.Warn},
+	} {
+		for i, level := range item.levels {
+			if level > 0 && averages[i] >= level {
+				return item.fn(fmt.Errorf(
+					"%s pressure is %s for %s tasks (%s)",
+					resource,
+					sensulib.PercentToHuman(averages[i], 2),
+					kind,
+					psiWindows[i],
+				))
+			}
+		}
+	}
+
+	return nil
+}
+
+func (conf *psiConfig) printMetrics(log *metrics.Metrics, pressure *measurements.Pressure) {
+	for _, item := range []struct {
+		kind string
+		stat *measurements.PressureStat
+	}{
+		{"some", pressure.Some},
+		{"full", pressure.Full},
+	} {
+		if item.stat == nil {
+			continue
+		}
+
+		for i, avg := range item.stat.Averages() {
+			log.Log(item.kind+"."+psiWindows[i], avg)
+		}
+
+		log.Log(item.kind+".total", item.stat.Total)
+	}
+}
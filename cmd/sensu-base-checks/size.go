@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var sizeRe = regexp.MustCompile(`^([0-9]+(?:\.[0-9]+)?)\s*([kmgtpe]?)(?:ib|b)?$`)
+
+// parseSize parses human-readable sizes, like 512, 10k, 1.5GiB. Units are
+// powers of 1024.
+func parseSize(size string) (uint64, error) {
+	matches := sizeRe.FindStringSubmatch(strings.ToLower(strings.TrimSpace(size)))
+	if matches == nil {
+		return 0, fmt.Errorf("invalid size %q", size)
+	}
+
+	value, err := strconv.ParseFloat(matches[1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q: %w", size, err)
+	}
+
+	if len(matches[2]) > 0 {
+		value *= math.Pow(1024, float64(strings.Index("kmgtpe", matches[2])+1))
+	}
+
+	// float64(math.MaxUint64) rounds up to 2^64, which doesn't fit
+	if value >= math.MaxUint64 {
+		return 0, fmt.Errorf("size %q is too large", size)
+	}
+
+	return uint64(value), nil
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/julian7/sensu-base-checks/measurements"
+	"github.com/julian7/sensu-base-checks/metrics"
+	"github.com/julian7/sensulib"
+	"github.com/spf13/cobra"
+)
+
+type tcpConfig struct {
+	Address  string
+	host     string
+	port     string
+	IPv4     bool
+	IPv6     bool
+	All      bool
+	Timeout  string
+	Send     string
+	Expect   string
+	Warn     string
+	warn     time.Duration
+	Crit     string
+	crit     time.Duration
+	Metrics  bool
+	probe    measurements.TCPProbe
+	dnsStart time.Time
+	dnsDone  time.Time
+}
+
+func tcpCmd() *cobra.Command {
+	config := &tcpConfig{}
+	cmd := sensulib.NewCommand(
+		config,
+		"tcp",
+		"TCP check",
+		`Checks for TCP services
+
+This check connects to a TCP port, optionally sends a payload, and matches
+received data against a regular expression. Returns
+
+- Unknown on configuration issues,
+- Warning on slow connection,
+- Critical on connection errors, banner mismatch, or very slow connection.
+
+Connection time includes name resolution. Payload can contain escape sequences
+(like \r\n). Zero latency thresholds are not checked.
+`,
+	)
+	flags := cmd.Flags()
+	flags.StringVarP(&config.Address, "address", "a", "127.0.0.1:80", "Target address in HOST:PORT form")
+	flags.BoolVarP(&config.IPv4, "ipv4", "4", false, "Connect over IPv4 only")
+	flags.BoolVarP(&config.IPv6, "ipv6", "6", false, "Connect over IPv6 only")
+	flags.BoolVarP(&config.All, "all", "A", false, "Check all resolved addresses")
+	flags.StringVarP(&config.Timeout, "timeout", "t", "5s", "Connection timeout")
+	flags.StringVarP(&config.Send, "send", "s", "", "Payload to send after connecting")
+	flags.StringVarP(&config.Expect, "expect", "e", "", "Expect received data to match this regular expression")
+	flags.StringVarP(&config.Warn, "warn", "w", "", "Warn if connection takes DURATION or longer")
+	flags.StringVarP(&config.Crit, "crit", "c", "", "Critical if connection takes DURATION or longer")
+	flags.BoolVar(&config.Metrics, "metrics", false, "Output measurements in OpenTSDB format")
+
+	return cmd
+}
+
+func (conf *tcpConfig) check() error {
+	var err error
+
+	conf.host, conf.port, err = net.SplitHostPort(conf.Address)
+	if err != nil {
+		return fmt.Errorf("cannot parse --address: %w", err)
+	}
+
+	if conf.IPv4 && conf.IPv6 {
+		return errors.New("--ipv4 and --ipv6 are mutually exclusive")
+	}
+
+	conf.probe.Network = "tcp"
+
+	switch {
+	case conf.IPv4:
+		conf.probe.Network = "tcp4"
+	case conf.IPv6:
+		conf.probe.Network = "tcp6"
+	}
+
+	conf.probe.Timeout, err = time.ParseDuration(conf.Timeout)
+	if err != nil {
+		return fmt.Errorf("cannot parse --timeout: %w", err)
+	}
+
+	if conf.probe.Timeout <= 0 {
+		return errors.New("--timeout should be set")
+	}
+
+	if len(conf.Send) > 0 {
+		payload, err := strconv.Unquote(`"` + conf.Send + `"`)
+		if err != nil {
+			return fmt.Errorf("cannot parse --send: %w", err)
+		}
+
+		conf.probe.Payload = []byte(payload)
+	}
+
+	if len(conf.Expect) > 0 {
+		conf.probe.Expect, err = regexp.Compile(conf.Expect)
+		if err != nil {
+			return fmt.Errorf("cannot compile --expect: %w", err)
+		}
+	}
+
+	for _, item := range []struct {
+		name   string
+		source string
+		target *time.Duration
+	}{
+		{"warn", conf.Warn, &conf.warn},
+		{"crit", conf.Crit, &conf.crit},
+	} {
+		if len(item.source) == 0 {
+			continue
+		}
+
+		*item.target, err = time.ParseDuration(item.source)
+		if err != nil {
+			return fmt.Errorf("cannot parse --%s: %w", item.name, err)
+		}
+	}
+
+	if conf.warn > 0 && conf.crit > 0 && conf.crit <= conf.warn {
+		return errors.New("--crit should be higher than --warn")
+	}
+
+	return nil
+}
+
+func (conf *tcpConfig) Run(cmd *cobra.Command, args []string) error {
+	if err := conf.check(); err != nil {
+		return sensulib.Unknown(err)
+	}
+
+	addresses, err := conf.resolve()
+	if err != nil {
+		return sensulib.Crit(err)
+	}
+
+	if !conf.All {
+		addresses = addresses[:1]
+	}
+
+	if conf.Metrics {
+		log := metrics.New("tcp").With(map[string]string{"host": conf.host, "port": conf.port})
+
+		for _, address := range addresses {
+			tracer := measurements.NewTCPTracer(conf.dnsStart, conf.dnsDone)
+			_, err := conf.probe.Probe(address, tracer)
+			ip, _, _ := net.SplitHostPort(address)
+
+			conf.printMetrics(log.With(map[string]string{"ip": ip}), tracer, err)
+		}
+
+		return nil
+	}
+
+	errs := sensulib.NewErrors()
+
+	var connect time.Duration
+
+	for _, address := range addresses {
+		tracer := measurements.NewTCPTracer(conf.dnsStart, conf.dnsDone)
+
+		if _, err := conf.probe.Probe(address, tracer); err != nil {
+			errs.Add(sensulib.Crit(fmt.Errorf("%s: %w", address, err)))
+			continue
+		}
+
+		errs.Add(conf.checkLatency(address, tracer.Connect()))
+
+		if tracer.Connect() > connect {
+			connect = tracer.Connect()
+		}
+	}
+
+	return errs.Return(sensulib.Ok(fmt.Errorf(
+		"%s connected in %s",
+		conf.Address,
+		connect.Round(time.Microsecond),
+	)))
+}
+
+// resolve returns addresses of the target host in HOST:PORT form
+func (conf *tcpConfig) resolve() ([]string, error) {
+	network := map[string]string{"tcp": "ip", "tcp4": "ip4", "tcp6": "ip6"}[conf.probe.Network]
+
+	ctx, cancel := context.WithTimeout(context.Background(), conf.probe.Timeout)
+	defer cancel()
+
+	conf.dnsStart = time.Now()
+	ips, err := net.DefaultResolver.LookupIP(ctx, network, conf.host)
+	conf.dnsDone = time.Now()
+
+	if err != nil {
+		return nil, fmt.Errorf("cannot resolve %s: %w", conf.host, err)
+	}
+
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("cannot resolve %s: no addresses found", conf.host)
+	}
+
+	addresses := make([]string, len(ips))
+	for i, ip := range ips {
+		addresses[i] = net.JoinHostPort(ip.String(), conf.port)
+	}
+
+	return addresses, nil
+}
+
+func (conf *tcpConfig) checkLatency(address string, connect time.Duration) *sensulib.Error {
+	for _, item := range []struct {
+		level time.Duration
+		fn    func(error) *sensulib.Error
+	}{
+		{conf.crit, sensulib.Crit},
+		{conf.warn, sensulib.Warn},
+	} {
+		if item.level > 0 && connect >= item.level {
+			return item.fn(fmt.Errorf(
+				"%s connected in %s, expected under %s",
+				address,
+				connect.Round(time.Microsecond),
+				item.level,
+			))
+		}
+	}
+
+	return nil
+}
+
+func (conf *tcpConfig) printMetrics(log *metrics.Metrics, tracer *measurements.TCPTracer, err error) {
+	log.Log("time.namelookup", tracer.Namelookup().Microseconds())
+
+	if !tracer.ConnDone.IsZero() {
+		log.Log("time.connect", tracer.Connect().Microseconds())
+	}
+
+	if !tracer.StartResponding.IsZero() {
+		log.Log("time.starttransfer", tracer.Starttransfer().Microseconds())
+	}
+
+	if !tracer.Finished.IsZero() {
+		log.Log("time.total", tracer.Total().Microseconds())
+	}
+
+	if err != nil {
+		log.Log("error", 1)
+	} else {
+		log.Log("error", 0)
+	}
+}
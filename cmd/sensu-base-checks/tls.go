@@ -0,0 +1,300 @@
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/julian7/sensu-base-checks/measurements"
+	"github.com/julian7/sensu-base-checks/metrics"
+	"github.com/julian7/sensulib"
+	"github.com/spf13/cobra"
+)
+
+type tlsConfig struct {
+	Address       string
+	host          string
+	port          string
+	ServerName    string
+	StartTLS      string
+	Timeout       string
+	timeout       time.Duration
+	Metrics       bool
+	tlsClient     measurements.TLSClient
+	inspector     measurements.TLSInspector
+	ocsp          measurements.OCSPChecker
+	expiry        measurements.CertExpiry
+	problems      []measurements.TLSProblem
+	connectTime   time.Duration
+	handshakeTime time.Duration
+}
+
+func tlsCmd() *cobra.Command {
+	config := &tlsConfig{}
+	cmd := sensulib.NewCommand(
+		config,
+		"tls",
+		"TLS check",
+		`Checks for TLS services
+
+This check connects to a TCP port, optionally negotiates TLS with STARTTLS,
+and inspects the TLS handshake. Returns
+
+- Unknown on configuration issues,
+- Warning on nearing TLS cert expiry,
+- Critical on connection or handshake errors.
+
+STARTTLS is supported for imap, ldap, mysql, pop3, postgres, and smtp. Cert
+expiry can be provided with longer range too (like d, w, mo).
+
+TLS inspection and OCSP checking work the same way as in the http check.
+`,
+	)
+	flags := cmd.Flags()
+	flags.StringVarP(&config.Address, "address", "a", "127.0.0.1:443", "Target address in HOST:PORT form")
+	flags.StringVarP(&config.ServerName, "server-name", "n", "", "Server name for SNI and hostname verification"+
+		" (default: host of --address)")
+	flags.StringVarP(&config.StartTLS, "starttls", "s", "", "Negotiate TLS with STARTTLS of protocol: "+
+		strings.Join(measurements.StartTLSProtocols(), ", "))
+	flags.StringVarP(&config.Timeout, "timeout", "t", "5s", "Connection timeout")
+	config.tlsClient.SetFlags(flags)
+	config.expiry.SetFlags(flags)
+	config.inspector.SetFlags(flags)
+	config.ocsp.SetFlags(flags)
+	flags.BoolVar(&config.Metrics, "metrics", false, "Output measurements in OpenTSDB format")
+
+	return cmd
+}
+
+func (conf *tlsConfig) check() error {
+	var err error
+
+	conf.host, conf.port, err = net.SplitHostPort(conf.Address)
+	if err != nil {
+		return fmt.Errorf("cannot parse --address: %w", err)
+	}
+
+	if len(conf.ServerName) == 0 {
+		conf.ServerName = conf.host
+	}
+
+	if len(conf.StartTLS) > 0 {
+		supported := false
+
+		for _, protocol := range measurements.StartTLSProtocols() {
+			if protocol == conf.StartTLS {
+				supported = true
+			}
+		}
+
+		if !supported {
+			return fmt.Errorf("--starttls should be one of %s", strings.Join(measurements.StartTLSProtocols(), ", "))
+		}
+	}
+
+	conf.timeout, err = time.ParseDuration(conf.Timeout)
+	if err != nil {
+		return fmt.Errorf("cannot parse --timeout: %w", err)
+	}
+
+	if conf.timeout <= 0 {
+		return errors.New("--timeout should be set")
+	}
+
+	if err := conf.tlsClient.Check(); err != nil {
+		return err
+	}
+
+	if err := conf.expiry.Check(); err != nil {
+		return err
+	}
+
+	if err := conf.inspector.Check(); err != nil {
+		return err
+	}
+
+	if err := conf.ocsp.Check(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (conf *tlsConfig) Run(cmd *cobra.Command, args []string) error {
+	if err := conf.check(); err != nil {
+		return sensulib.Unknown(err)
+	}
+
+	tlsconfig, err := conf.clientConfig()
+	if err != nil {
+		return sensulib.Unknown(err)
+	}
+
+	cs, err := conf.handshake(tlsconfig)
+
+	if conf.Metrics {
+		conf.printMetrics(cs, err)
+
+		return nil
+	}
+
+	if err != nil {
+		return conf.checkProblems(sensulib.Crit(err))
+	}
+
+	if err := conf.checkProblems(nil); err != nil {
+		return err
+	}
+
+	if err := conf.expiry.Verify(cs.PeerCertificates); err != nil {
+		return err
+	}
+
+	if conf.ocsp.Enabled() {
+		if err := conf.ocsp.Verify(*cs); err != nil {
+			return err
+		}
+	}
+
+	msg := fmt.Sprintf(
+		"TLS handshake with %s succeeded in %s: %s, %s; certificate %s expires in %s",
+		conf.Address,
+		conf.handshakeTime.Round(time.Microsecond),
+		measurements.TLSVersionName(cs.Version),
+		tls.CipherSuiteName(cs.CipherSuite),
+		cs.PeerCertificates[0].Subject,
+		measurements.ExpiresIn(cs.PeerCertificates[0]),
+	)
+
+	if conf.inspector.Enabled() {
+		msg += "\n" + strings.Join(measurements.ChainSummary(cs.PeerCertificates), "\n")
+	}
+
+	return sensulib.Ok(errors.New(msg))
+}
+
+func (conf *tlsConfig) clientConfig() (*tls.Config, error) {
+	tlsconfig, err := conf.tlsClient.Config()
+	if err != nil {
+		return nil, err
+	}
+
+	tlsconfig.ServerName = conf.ServerName
+
+	verify := !tlsconfig.InsecureSkipVerify
+	roots := tlsconfig.RootCAs
+	conf.ocsp.Roots = roots
+	conf.ocsp.Client = &http.Client{Timeout: conf.timeout}
+
+	if !conf.inspector.Enabled() {
+		return tlsconfig, nil
+	}
+
+	// verification is done by the inspector to report problems with their
+	// own alert levels
+	tlsconfig.InsecureSkipVerify = true
+	tlsconfig.VerifyConnection = func(cs tls.ConnectionState) error {
+		conf.problems = conf.inspector.Inspect(cs, conf.ServerName, roots, verify)
+
+		for _, problem := range conf.problems {
+			if conf.inspector.Fatal(problem) {
+				return errors.New("TLS inspection failed")
+			}
+		}
+
+		return nil
+	}
+
+	return tlsconfig, nil
+}
+
+// handshake connects to the target, and returns the TLS connection state
+func (conf *tlsConfig) handshake(tlsconfig *tls.Config) (*tls.ConnectionState, error) {
+	start := time.Now()
+
+	conn, err := net.DialTimeout("tcp", conf.Address, conf.timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	defer conn.Close()
+
+	conf.connectTime = time.Since(start)
+
+	if err := conn.SetDeadline(start.Add(conf.timeout)); err != nil {
+		return nil, err
+	}
+
+	if len(conf.StartTLS) > 0 {
+		hostname, err := os.Hostname()
+		if err != nil {
+			hostname = "localhost"
+		}
+
+		if err := measurements.StartTLS(conn, conf.StartTLS, hostname); err != nil {
+			return nil, err
+		}
+	}
+
+	handshakeStart := time.Now()
+	tlsConn := tls.Client(conn, tlsconfig)
+
+	if err := tlsConn.Handshake(); err != nil {
+		return nil, fmt.Errorf("TLS handshake with %s: %w", conf.Address, err)
+	}
+
+	conf.handshakeTime = time.Since(handshakeStart)
+	cs := tlsConn.ConnectionState()
+
+	return &cs, nil
+}
+
+// checkProblems returns TLS inspection problems, or def if there are none
+func (conf *tlsConfig) checkProblems(def *sensulib.Error) error {
+	errs := sensulib.NewErrors()
+
+	for _, err := range conf.inspector.Errors(conf.problems) {
+		errs.Add(err)
+	}
+
+	if def == nil {
+		return errs.Return(nil)
+	}
+
+	return errs.Return(def)
+}
+
+func (conf *tlsConfig) printMetrics(cs *tls.ConnectionState, err error) {
+	log := metrics.New("tls").With(map[string]string{"host": conf.host, "port": conf.port})
+
+	if conf.connectTime > 0 {
+		log.Log("time.connect", conf.connectTime.Microseconds())
+	}
+
+	if cs != nil {
+		log.Log("time.handshake", conf.handshakeTime.Microseconds())
+
+		for depth, cert := range cs.PeerCertificates {
+			log.With(map[string]string{
+				"depth": strconv.Itoa(depth),
+				"cn":    cert.Subject.CommonName,
+			}).Log("cert.days_left", measurements.DaysLeft(cert))
+		}
+	}
+
+	if conf.inspector.Enabled() {
+		log.Log("problems", len(conf.problems))
+	}
+
+	if err != nil {
+		log.Log("error", 1)
+	} else {
+		log.Log("error", 0)
+	}
+}
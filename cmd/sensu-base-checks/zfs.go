@@ -0,0 +1,262 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/julian7/sensu-base-checks/measurements"
+	"github.com/julian7/sensu-base-checks/metrics"
+	"github.com/julian7/sensulib"
+	"github.com/karrick/tparse"
+	"github.com/spf13/cobra"
+)
+
+type zfsConfig struct {
+	Pools    []string
+	Zpool    string
+	CWarn    float64
+	CCrit    float64
+	FWarn    float64
+	FCrit    float64
+	ScrubAge string
+	scrubAge time.Time
+	Metrics  bool
+	run      measurements.CommandRunner
+}
+
+func zfsCmd() *cobra.Command {
+	config := &zfsConfig{run: measurements.ExecRunner}
+	cmd := sensulib.NewCommand(
+		config,
+		"zfs",
+		"ZFS pool check",
+		`Checks for ZFS pool health and capacity
+
+This check runs "zpool list" and "zpool status", and inspects pool states,
+capacity, fragmentation, last scrub time and errors. Returns
+
+- Unknown on configuration issues, or if zpool cannot be run,
+- Warning on degraded pools, and on nearing thresholds,
+- Critical on faulted or unavailable pools, data errors, or crossed thresholds.
+
+Scrub age can be provided in long range too (like d, w, mo).
+`,
+	)
+	flags := cmd.Flags()
+	flags.StringSliceVarP(&config.Pools, "pool", "P", nil, "Check only these pools")
+	flags.StringVar(&config.Zpool, "zpool", "zpool", "zpool command")
+	flags.Float64VarP(&config.CWarn, "cwarn", "w", 80.0, "Warn if PERCENT or more of pool is allocated; (0,100]")
+	flags.Float64VarP(&config.CCrit, "ccrit", "c", 90.0, "Critical if PERCENT or more of pool is allocated; (0,100]")
+	flags.Float64VarP(&config.FWarn, "fwarn", "W", 0, "Warn if free space fragmentation is PERCENT or more; 0 disables")
+	flags.Float64VarP(&config.FCrit, "fcrit", "C", 0, "Critical if free space fragmentation is PERCENT or more; 0 disables")
+	flags.StringVarP(&config.ScrubAge, "scrub-age", "s", "", "Warn if last scrub is older than this duration (like 5w)")
+	flags.BoolVar(&config.Metrics, "metrics", false, "Output measurements in OpenTSDB format")
+
+	return cmd
+}
+
+func (conf *zfsConfig) check() error {
+	if len(conf.ScrubAge) != 0 {
+		var err error
+
+		conf.scrubAge, err = tparse.ParseNow(time.RFC3339, "now-"+conf.ScrubAge)
+		if err != nil {
+			return fmt.Errorf("cannot parse --scrub-age: %w", err)
+		}
+	}
+
+	if conf.Metrics {
+		return nil
+	}
+
+	checks := []struct {
+		name   string
+		check  bool
+		errstr string
+	}{
+		{"cwarn", conf.CWarn <= 0, "higher than 0"},
+		{"cwarn", conf.CWarn > 100, "at most 100"},
+		{"ccrit", conf.CCrit <= 0, "higher than 0"},
+		{"ccrit", conf.CCrit > 100, "at most 100"},
+		{"ccrit", conf.CCrit <= conf.CWarn, "should be higher than --cwarn"},
+		{"fwarn", conf.FWarn < 0, "at least 0"},
+		{"fcrit", conf.FCrit < 0, "at least 0"},
+		{"fcrit", conf.FWarn > 0 && conf.FCrit > 0 && conf.FCrit <= conf.FWarn, "should be higher than --fwarn"},
+	}
+
+	for _, check := range checks {
+		if check.check {
+			return fmt.Errorf("--%s should be %s", check.name, check.errstr)
+		}
+	}
+
+	return nil
+}
+
+func (conf *zfsConfig) Run(cmd *cobra.Command, args []string) error {
+	if err := conf.check(); err != nil {
+		return sensulib.Unknown(err)
+	}
+
+	pools, err := measurements.ZPools(conf.run, conf.Zpool)
+	if err != nil {
+		return sensulib.Unknown(err)
+	}
+
+	var log *metrics.Metrics
+
+	if conf.Metrics {
+		log = metrics.New("zfs")
+	}
+
+	errs := sensulib.NewErrors()
+	count := 0
+
+	for _, pool := range pools {
+		if len(conf.Pools) > 0 && !contains(conf.Pools, pool.Name) {
+			continue
+		}
+
+		count++
+
+		if conf.Metrics {
+			conf.measurePool(log, pool)
+			continue
+		}
+
+		for _, err := range conf.checkPool(pool) {
+			errs.Add(err)
+		}
+	}
+
+	if count == 0 && !conf.Metrics {
+		return sensulib.Crit(errors.New("no ZFS pools found"))
+	}
+
+	if conf.Metrics {
+		return nil
+	}
+
+	return errs.Return(sensulib.Ok(fmt.Errorf(
+		"all %d ZFS pools are online and under %s allocation",
+		count,
+		sensulib.PercentToHuman(conf.CWarn, 1),
+	)))
+}
+
+func (conf *zfsConfig) checkPool(pool *measurements.ZPool) []*sensulib.Error {
+	var errs []*sensulib.Error
+
+	switch pool.Health {
+	case "ONLINE":
+	case "DEGRADED":
+		errs = append(errs, sensulib.Warn(fmt.Errorf("pool %s is %s", pool.Name, pool.Health)))
+	default:
+		errs = append(errs, sensulib.Crit(fmt.Errorf("pool %s is %s", pool.Name, pool.Health)))
+	}
+
+	if len(pool.DataErrors) > 0 {
+		errs = append(errs, sensulib.Crit(fmt.Errorf("pool %s has %s", pool.Name, pool.DataErrors)))
+	}
+
+	if pool.Capacity >= conf.CWarn {
+		err := fmt.Errorf(
+			"pool %s %s allocated (%s free of %s)",
+			pool.Name,
+			sensulib.PercentToHuman(pool.Capacity, 0),
+			sensulib.SizeToHuman(pool.Free),
+			sensulib.SizeToHuman(pool.Size),
+		)
+
+		if pool.Capacity >= conf.CCrit {
+			errs = append(errs, sensulib.Crit(err))
+		} else {
+			errs = append(errs, sensulib.Warn(err))
+		}
+	}
+
+	if err := conf.checkFragmentation(pool); err != nil {
+		errs = append(errs, err)
+	}
+
+	if err := conf.checkScrub(pool); err != nil {
+		errs = append(errs, err)
+	}
+
+	return errs
+}
+
+func (conf *zfsConfig) checkFragmentation(pool *measurements.ZPool) *sensulib.Error {
+	err := fmt.Errorf("pool %s is %s fragmented", pool.Name, sensulib.PercentToHuman(pool.Fragmentation, 0))
+
+	switch {
+	case pool.Fragmentation < 0:
+		return nil
+	case conf.FCrit > 0 && pool.Fragmentation >= conf.FCrit:
+		return sensulib.Crit(err)
+	case conf.FWarn > 0 && pool.Fragmentation >= conf.FWarn:
+		return sensulib.Warn(err)
+	}
+
+	return nil
+}
+
+func (conf *zfsConfig) checkScrub(pool *measurements.ZPool) *sensulib.Error {
+	if pool.ScrubErrors > 0 {
+		return sensulib.Crit(fmt.Errorf("pool %s last scrub had %d errors", pool.Name, pool.ScrubErrors))
+	}
+
+	if conf.scrubAge.IsZero() || pool.Scrubbing {
+		return nil
+	}
+
+	if pool.Scrubbed.IsZero() {
+		return sensulib.Warn(fmt.Errorf("pool %s has never been scrubbed", pool.Name))
+	}
+
+	if pool.Scrubbed.Before(conf.scrubAge) {
+		return sensulib.Warn(fmt.Errorf(
+			"pool %s was last scrubbed %s ago",
+			pool.Name,
+			measurements.HumanDuration(time.Since(pool.Scrubbed)),
+		))
+	}
+
+	return nil
+}
+
+func (conf *zfsConfig) measurePool(log *metrics.Metrics, pool *measurements.ZPool) {
+	log = log.With(map[string]string{"pool": pool.Name})
+
+	online := 0
+	if pool.Health == "ONLINE" {
+		online = 1
+	}
+
+	log.Log("bytes.total", pool.Size)
+	log.Log("bytes.allocated", pool.Allocated)
+	log.Log("bytes.free", pool.Free)
+	log.Log("capacity", pool.Capacity)
+
+	if pool.Fragmentation >= 0 {
+		log.Log("fragmentation", pool.Fragmentation)
+	}
+
+	log.Log("online", online)
+
+	if !pool.Scrubbed.IsZero() {
+		log.Log("scrub.age", int64(time.Since(pool.Scrubbed).Seconds()))
+		log.Log("scrub.errors", pool.ScrubErrors)
+	}
+}
+
+func contains(haystack []string, needle string) bool {
+	for _, item := range haystack {
+		if item == needle {
+			return true
+		}
+	}
+
+	return false
+}
@@ -11,9 +11,12 @@ require (
 	github.com/julian7/sensulib v0.4.1
 	github.com/karrick/tparse v2.4.2+incompatible
 	github.com/magefile/mage v1.12.1
+	github.com/santhosh-tekuri/jsonschema/v5 v5.0.0
 	github.com/shirou/gopsutil/v3 v3.22.1
 	github.com/spf13/cobra v1.3.0
 	github.com/spf13/pflag v1.0.5
+	golang.org/x/crypto v0.0.0-20220214200702-86341886e292
+	golang.org/x/sys v0.0.0-20220224120231-95c6836cb0e7
 )
 
 require (
@@ -32,10 +35,8 @@ require (
 	github.com/spf13/afero v1.8.1 // indirect
 	github.com/xanzy/go-gitlab v0.55.1 // indirect
 	github.com/yusufpapurcu/wmi v1.2.2 // indirect
-	golang.org/x/crypto v0.0.0-20220214200702-86341886e292 // indirect
 	golang.org/x/net v0.0.0-20220225172249-27dd8689420f // indirect
 	golang.org/x/oauth2 v0.0.0-20220223155221-ee480838109b // indirect
-	golang.org/x/sys v0.0.0-20220224120231-95c6836cb0e7 // indirect
 	golang.org/x/text v0.3.7 // indirect
 	golang.org/x/time v0.0.0-20220224211638-0e9765cccd65 // indirect
 	google.golang.org/appengine v1.6.7 // indirect
@@ -0,0 +1,102 @@
+package measurements
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"regexp"
+
+	"github.com/julian7/sensulib"
+	"github.com/spf13/pflag"
+)
+
+// BodyCheck reads response bodies, and checks them against regular
+// expressions
+type BodyCheck struct {
+	MaxBytes  uint64 // read limit; 0 reads the whole body
+	matchS    []string
+	match     []*regexp.Regexp
+	notMatchS []string
+	notMatch  []*regexp.Regexp
+	snippet   uint
+}
+
+func (conf *BodyCheck) SetFlags(flags *pflag.FlagSet) {
+	flags.StringArrayVar(&conf.matchS, "body-match", nil, "Critical if response body doesn't match"+
+		" regular expression (repeatable)")
+	flags.StringArrayVar(&conf.notMatchS, "body-not-match", nil, "Critical if response body matches"+
+		" regular expression (repeatable)")
+	flags.UintVar(&conf.snippet, "snippet", 0, "Show at most COUNT bytes of response body on content check failures")
+}
+
+func (conf *BodyCheck) Check() error {
+	for _, item := range []struct {
+		name   string
+		source []string
+		target *[]*regexp.Regexp
+	}{
+		{"body-match", conf.matchS, &conf.match},
+		{"body-not-match", conf.notMatchS, &conf.notMatch},
+	} {
+		*item.target = make([]*regexp.Regexp, len(item.source))
+
+		for i, expr := range item.source {
+			re, err := regexp.Compile(expr)
+			if err != nil {
+				return fmt.Errorf("cannot interpret regexp from --%s: %w", item.name, err)
+			}
+
+			(*item.target)[i] = re
+		}
+	}
+
+	return nil
+}
+
+// Enabled returns true if body content is checked
+func (conf *BodyCheck) Enabled() bool {
+	return len(conf.match) > 0 || len(conf.notMatch) > 0
+}
+
+// Read reads the response body up to the read limit
+func (conf *BodyCheck) Read(body io.Reader) ([]byte, error) {
+	if conf.MaxBytes > 0 {
+		body = io.LimitReader(body, int64(conf.MaxBytes))
+	}
+
+	return ioutil.ReadAll(body)
+}
+
+// Snippet returns the beginning of the response body for failure messages,
+// if requested
+func (conf *BodyCheck) Snippet(body []byte) string {
+	if conf.snippet == 0 {
+		return ""
+	}
+
+	if uint(len(body)) > conf.snippet {
+		return fmt.Sprintf("; body: %q...", body[:conf.snippet])
+	}
+
+	return fmt.Sprintf("; body: %q", body)
+}
+
+// CheckContent returns a critical error for each expression the body doesn't
+// match, and for each expression the body matches but it shouldn't
+func (conf *BodyCheck) CheckContent(body []byte) error {
+	errs := sensulib.NewErrors()
+
+	for _, re := range conf.match {
+		if !re.Match(body) {
+			errs.Add(sensulib.Crit(fmt.Errorf("response body doesn't match %q%s", re, conf.Snippet(body))))
+		}
+	}
+
+	for _, re := range conf.notMatch {
+		if re.Match(body) {
+			errs.Add(sensulib.Crit(fmt.Errorf("response body matches %q%s", re, conf.Snippet(body))))
+		}
+	}
+
+	return errs.Return(nil)
+}
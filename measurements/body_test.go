@@ -0,0 +1,160 @@
+package measurements
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/julian7/sensulib"
+)
+
+func TestBodyCheck_Check(t *testing.T) {
+	tests := []struct {
+		name     string
+		match    []string
+		notMatch []string
+		enabled  bool
+		wantErr  string
+	}{
+		{"disabled", nil, nil, false, ""},
+		{"match", []string{"^ok$"}, nil, true, ""},
+		{"not match", nil, []string{"error"}, true, ""},
+		{"invalid match", []string{"("}, nil, false, "--body-match"},
+		{"invalid not match", nil, []string{"ok", "["}, false, "--body-not-match"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			conf := &BodyCheck{matchS: tt.match, notMatchS: tt.notMatch}
+
+			err := conf.Check()
+
+			switch {
+			case len(tt.wantErr) == 0 && err != nil:
+				t.Fatalf("Check() error = %v, wanted none", err)
+			case len(tt.wantErr) > 0 && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Fatalf("Check() error = %v, wanted %q", err, tt.wantErr)
+			}
+
+			if err == nil && conf.Enabled() != tt.enabled {
+				t.Errorf("Enabled() = %v, want %v", conf.Enabled(), tt.enabled)
+			}
+		})
+	}
+}
+
+func TestBodyCheck_Read(t *testing.T) {
+	tests := []struct {
+		name     string
+		maxBytes uint64
+		want     string
+	}{
+		{"unlimited", 0, "hello world"},
+		{"limited", 5, "hello"},
+		{"limit over length", 100, "hello world"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			conf := &BodyCheck{MaxBytes: tt.maxBytes}
+
+			got, err := conf.Read(strings.NewReader("hello world"))
+			if err != nil {
+				t.Fatalf("Read() error = %v", err)
+			}
+
+			if string(got) != tt.want {
+				t.Errorf("Read() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBodyCheck_Snippet(t *testing.T) {
+	tests := []struct {
+		name    string
+		snippet uint
+		want    string
+	}{
+		{"disabled", 0, ""},
+		{"truncated", 5, `; body: "hello"...`},
+		{"whole body", 11, `; body: "hello world"`},
+		{"longer than body", 100, `; body: "hello world"`},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			conf := &BodyCheck{snippet: tt.snippet}
+
+			if got := conf.Snippet([]byte("hello world")); got != tt.want {
+				t.Errorf("Snippet() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBodyCheck_CheckContent(t *testing.T) {
+	failures := func(msgs ...string) error {
+		errs := sensulib.NewErrors()
+
+		for _, msg := range msgs {
+			errs.Add(sensulib.Crit(errors.New(msg)))
+		}
+
+		return errs.Return(nil)
+	}
+
+	tests := []struct {
+		name     string
+		match    []string
+		notMatch []string
+		snippet  uint
+		want     error
+	}{
+		{"no patterns", nil, nil, 0, nil},
+		{"matching", []string{"^status: ok", "ok$"}, []string{"error"}, 0, nil},
+		{
+			"not matching",
+			[]string{"^status: ok", "ready"},
+			nil,
+			0,
+			failures(`response body doesn't match "ready"`),
+		},
+		{
+			"matching forbidden pattern",
+			nil,
+			[]string{"error", "status"},
+			0,
+			failures(`response body matches "status"`),
+		},
+		{
+			"every failure reported",
+			[]string{"ready", "up"},
+			[]string{"ok"},
+			6,
+			failures(
+				`response body doesn't match "ready"; body: "status"...`,
+				`response body doesn't match "up"; body: "status"...`,
+				`response body matches "ok"; body: "status"...`,
+			),
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			conf := &BodyCheck{matchS: tt.match, notMatchS: tt.notMatch, snippet: tt.snippet}
+			if err := conf.Check(); err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := deep.Equal(conf.CheckContent([]byte("status: ok")), tt.want); diff != nil {
+				t.Error(diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package measurements
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// BtrfsSysfs is the default location of btrfs information in sysfs
+const BtrfsSysfs = "/sys/fs/btrfs"
+
+// BtrfsSpace contains chunk allocation of a block group type
+type BtrfsSpace struct {
+	Used      uint64 // bytes used in allocated chunks
+	Total     uint64 // bytes allocated to chunks
+	DiskTotal uint64 // raw bytes allocated to chunks, including redundancy
+}
+
+// BtrfsAllocation contains chunk allocation information of a btrfs filesystem
+type BtrfsAllocation struct {
+	UUID       string
+	Data       BtrfsSpace
+	Metadata   BtrfsSpace
+	System     BtrfsSpace
+	DeviceSize uint64
+}
+
+// Unallocated returns raw device bytes not allocated to any chunks
+func (alloc *BtrfsAllocation) Unallocated() uint64 {
+	allocated := alloc.Data.DiskTotal + alloc.Metadata.DiskTotal + alloc.System.DiskTotal
+	if allocated > alloc.DeviceSize {
+		return 0
+	}
+
+	return alloc.DeviceSize - allocated
+}
+
+// DataPercent returns data usage, counting unallocated space as usable
+func (alloc *BtrfsAllocation) DataPercent() float64 {
+	return alloc.percent(&alloc.Data)
+}
+
+// MetadataPercent returns metadata usage, counting unallocated space as usable
+func (alloc *BtrfsAllocation) MetadataPercent() float64 {
+	return alloc.percent(&alloc.Metadata)
+}
+
+func (alloc *BtrfsAllocation) percent(space *BtrfsSpace) float64 {
+	available := float64(space.Total)
+
+	if space.DiskTotal > 0 {
+		// unallocated raw space usable for this type, considering redundancy
+		available += float64(alloc.Unallocated()) * float64(space.Total) / float64(space.DiskTotal)
+	}
+
+	if available == 0 {
+		return 0
+	}
+
+	return float64(space.Used) / available * 100
+}
+
+// BtrfsAllocations reads chunk allocation of btrfs filesystems from sysfs.
+// It returns filesystems by their block device names (like sda2, or dm-0).
+func BtrfsAllocations(sysfs string) (map[string]*BtrfsAllocation, error) {
+	entries, err := os.ReadDir(sysfs)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return map[string]*BtrfsAllocation{}, nil
+		}
+
+		return nil, err
+	}
+
+	ret := map[string]*BtrfsAllocation{}
+
+	for _, entry := range entries {
+		fsdir := filepath.Join(sysfs, entry.Name())
+		if _, err := os.Stat(filepath.Join(fsdir, "allocation")); err != nil {
+			continue
+		}
+
+		alloc, devices, err := readBtrfsAllocation(fsdir)
+		if err != nil {
+			return nil, err
+		}
+
+		alloc.UUID = entry.Name()
+
+		for _, dev := range devices {
+			ret[dev] = alloc
+		}
+	}
+
+	return ret, nil
+}
+
+func readBtrfsAllocation(fsdir string) (*BtrfsAllocation, []string, error) {
+	alloc := &BtrfsAllocation{}
+
+	for _, space := range []struct {
+		name   string
+		target *BtrfsSpace
+	}{
+		{"data", &alloc.Data},
+		{"metadata", &alloc.Metadata},
+		{"system", &alloc.System},
+	} {
+		for _, item := range []struct {
+			name   string
+			target *uint64
+		}{
+			{"bytes_used", &space.target.Used},
+			{"total_bytes", &space.target.Total},
+			{"disk_total", &space.target.DiskTotal},
+		} {
+			val, err := readUintFile(filepath.Join(fsdir, "allocation", space.name, item.name))
+			if err != nil {
+				return nil, nil, err
+			}
+
+			*item.target = val
+		}
+	}
+
+	entries, err := os.ReadDir(filepath.Join(fsdir, "devices"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	devices := make([]string, 0, len(entries))
+
+	for _, entry := range entries {
+		// size is in 512 byte sectors
+		sectors, err := readUintFile(filepath.Join(fsdir, "devices", entry.Name(), "size"))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		alloc.DeviceSize += sectors * 512
+
+		devices = append(devices, entry.Name())
+	}
+
+	return alloc, devices, nil
+}
+
+func readUintFile(path string) (uint64, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	val, err := strconv.ParseUint(strings.TrimSpace(string(contents)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parsing %s: %w", path, err)
+	}
+
+	return val, nil
+}
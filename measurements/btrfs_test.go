@@ -0,0 +1,39 @@
+package measurements
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+)
+
+func TestBtrfsAllocations(t *testing.T) {
+	allocs, err := BtrfsAllocations(filepath.Join("testdata", "btrfs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(allocs) != 1 {
+		t.Fatalf("BtrfsAllocations() returned %d devices, want 1", len(allocs))
+	}
+
+	alloc, ok := allocs["sda2"]
+	if !ok {
+		t.Fatal("sda2 is not found")
+	}
+
+	const gib = 1 << 30
+
+	if got, want := alloc.Unallocated(), uint64(20*gib-16*gib-2*gib-64<<20); got != want {
+		t.Errorf("Unallocated() = %d, want %d", got, want)
+	}
+
+	// 15 GiB used of 16 GiB allocated + ~1.94 GiB unallocated
+	if got := alloc.DataPercent(); math.Abs(got-83.6) > 0.1 {
+		t.Errorf("DataPercent() = %f, want ~83.6", got)
+	}
+
+	// 0.9 GiB used of 1 GiB allocated + ~0.97 GiB unallocated with DUP profile
+	if got := alloc.MetadataPercent(); math.Abs(got-45.8) > 0.1 {
+		t.Errorf("MetadataPercent() = %f, want ~45.8", got)
+	}
+}
@@ -0,0 +1,67 @@
+package measurements
+
+import (
+	"crypto/x509"
+	"fmt"
+	"time"
+
+	"github.com/hako/durafmt"
+	"github.com/julian7/sensulib"
+	"github.com/karrick/tparse"
+	"github.com/spf13/pflag"
+)
+
+// CertExpiry warns on certificates nearing their expiry
+type CertExpiry struct {
+	expiryS string
+	expiry  time.Time
+}
+
+func (conf *CertExpiry) SetFlags(flags *pflag.FlagSet) {
+	flags.StringVarP(&conf.expiryS, "expiry", "e", "", "Warn EXPIRY before cert expires (duration, like 5d)")
+}
+
+func (conf *CertExpiry) Check() error {
+	if len(conf.expiryS) == 0 {
+		return nil
+	}
+
+	var err error
+
+	conf.expiry, err = tparse.ParseNow(time.RFC3339, "now+"+conf.expiryS)
+	if err != nil {
+		return fmt.Errorf("cannot parse --expiry: %w", err)
+	}
+
+	return nil
+}
+
+// Enabled returns true if expiry check is requested
+func (conf *CertExpiry) Enabled() bool {
+	return len(conf.expiryS) != 0
+}
+
+// Verify returns a warning if any of the certificates expires within the
+// expiry duration
+func (conf *CertExpiry) Verify(certs []*x509.Certificate) *sensulib.Error {
+	if !conf.Enabled() {
+		return nil
+	}
+
+	for _, cert := range certs {
+		if cert.NotAfter.Before(conf.expiry) {
+			return sensulib.Warn(fmt.Errorf(
+				"certificate %s will expire in %s",
+				cert.Subject,
+				ExpiresIn(cert),
+			))
+		}
+	}
+
+	return nil
+}
+
+// ExpiresIn returns human readable time until the certificate expires
+func ExpiresIn(cert *x509.Certificate) string {
+	return durafmt.Parse(time.Until(cert.NotAfter)).LimitFirstN(4).String()
+}
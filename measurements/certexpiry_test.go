@@ -0,0 +1,39 @@
+package measurements
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"testing"
+	"time"
+)
+
+func TestCertExpiry_Verify(t *testing.T) {
+	certs := []*x509.Certificate{
+		{Subject: pkix.Name{CommonName: "leaf"}, NotAfter: time.Now().Add(10 * 24 * time.Hour)},
+		{Subject: pkix.Name{CommonName: "ca"}, NotAfter: time.Now().Add(100 * 24 * time.Hour)},
+	}
+	tests := []struct {
+		name    string
+		expiry  string
+		wantErr bool
+	}{
+		{"disabled", "", false},
+		{"far from expiry", "5d", false},
+		{"leaf nearing expiry", "20d", true},
+		{"CA nearing expiry", "20w", true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			conf := &CertExpiry{expiryS: tt.expiry}
+			if err := conf.Check(); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := conf.Verify(certs); (err != nil) != tt.wantErr {
+				t.Errorf("CertExpiry.Verify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
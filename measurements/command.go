@@ -0,0 +1,26 @@
+package measurements
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// CommandRunner runs an external command, and returns its standard output
+type CommandRunner func(name string, args ...string) ([]byte, error)
+
+// ExecRunner runs commands with os/exec
+func ExecRunner(name string, args ...string) ([]byte, error) {
+	out, err := exec.Command(name, args...).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return nil, fmt.Errorf("running %s: %w: %s", name, err, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+
+		return nil, fmt.Errorf("running %s: %w", name, err)
+	}
+
+	return out, nil
+}
@@ -0,0 +1,72 @@
+package measurements
+
+import (
+	"github.com/shirou/gopsutil/v3/cpu"
+)
+
+// CPUUsage contains CPU time breakdown over a time period, in percent
+type CPUUsage struct {
+	CPU     string
+	User    float64
+	Nice    float64
+	System  float64
+	Idle    float64
+	Iowait  float64
+	Irq     float64
+	Softirq float64
+	Steal   float64
+}
+
+// Busy returns percentage of time the CPU was not idle or waiting for I/O
+func (usage *CPUUsage) Busy() float64 {
+	return 100 - usage.Idle - usage.Iowait
+}
+
+// CPUUsageBetween calculates CPU time breakdown between two CPU time samples
+func CPUUsageBetween(prev, cur cpu.TimesStat) *CPUUsage {
+	// guest times are included in user and nice times on linux
+	fields := []struct {
+		prev float64
+		cur  float64
+	}{
+		{prev.User, cur.User},
+		{prev.Nice, cur.Nice},
+		{prev.System, cur.System},
+		{prev.Idle, cur.Idle},
+		{prev.Iowait, cur.Iowait},
+		{prev.Irq, cur.Irq},
+		{prev.Softirq, cur.Softirq},
+		{prev.Steal, cur.Steal},
+	}
+
+	deltas := make([]float64, len(fields))
+	total := 0.0
+
+	for i, field := range fields {
+		if field.cur > field.prev {
+			deltas[i] = field.cur - field.prev
+			total += deltas[i]
+		}
+	}
+
+	usage := &CPUUsage{CPU: cur.CPU}
+	if total == 0 {
+		usage.Idle = 100
+		return usage
+	}
+
+	for i, target := range []*float64{
+		&usage.User,
+		&usage.Nice,
+		&usage.System,
+		&usage.Idle,
+		&usage.Iowait,
+		&usage.Irq,
+		&usage.Softirq,
+		&usage.Steal,
+	} {
+		*target = deltas[i] / total * 100
+	}
+
+	return usage
+}
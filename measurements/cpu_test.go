@@ -0,0 +1,43 @@
+package measurements
+
+import (
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/shirou/gopsutil/v3/cpu"
+)
+
+func TestCPUUsageBetween(t *testing.T) {
+	tests := []struct {
+		name string
+		prev cpu.TimesStat
+		cur  cpu.TimesStat
+		want *CPUUsage
+	}{
+		{
+			"breakdown",
+			cpu.TimesStat{CPU: "cpu0", User: 100, System: 50, Idle: 1000, Iowait: 10, Steal: 5},
+			cpu.TimesStat{CPU: "cpu0", User: 140, System: 60, Idle: 1040, Iowait: 15, Steal: 10},
+			&CPUUsage{CPU: "cpu0", User: 40, System: 10, Idle: 40, Iowait: 5, Steal: 5},
+		},
+		{
+			"no change",
+			cpu.TimesStat{CPU: "cpu-total", User: 100, Idle: 1000},
+			cpu.TimesStat{CPU: "cpu-total", User: 100, Idle: 1000},
+			&CPUUsage{CPU: "cpu-total", Idle: 100},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CPUUsageBetween(tt.prev, tt.cur)
+			if diff := deep.Equal(tt.want, got); diff != nil {
+				t.Error(diff)
+			}
+
+			if busy, want := got.Busy(), 100-tt.want.Idle-tt.want.Iowait; busy != want {
+				t.Errorf("Busy() = %f, want %f", busy, want)
+			}
+		})
+	}
+}
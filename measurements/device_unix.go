@@ -0,0 +1,18 @@
+//go:build !windows
+// +build !windows
+
+package measurements
+
+import (
+	"os"
+	"syscall"
+)
+
+func deviceID(info os.FileInfo) (uint64, bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+
+	return uint64(st.Dev), true
+}
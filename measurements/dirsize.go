@@ -0,0 +1,171 @@
+package measurements
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+
+	"github.com/spf13/pflag"
+)
+
+// DirStat contains aggregated measurements of a directory tree. Counters
+// are updated atomically, and they are kept first for 64-bit alignment.
+type DirStat struct {
+	Bytes  uint64
+	Files  uint64
+	Dirs   uint64
+	Errors []error
+}
+
+// DirWalker walks directory trees, reading directories concurrently
+type DirWalker struct {
+	maxDepth    int
+	excludes    []string
+	oneFS       bool
+	concurrency int
+}
+
+func (conf *DirWalker) SetFlags(flags *pflag.FlagSet) {
+	flags.IntVar(&conf.maxDepth, "depth", 0, "Maximum depth to descend to; 0 means unlimited")
+	flags.StringSliceVar(&conf.excludes, "exclude", nil, "Ignore entries matching glob, by name or by relative path")
+	flags.BoolVar(&conf.oneFS, "one-file-system", false, "Skip directories on different filesystems")
+	flags.IntVar(&conf.concurrency, "concurrency", 4, "Number of directories read concurrently")
+}
+
+func (conf *DirWalker) Check() error {
+	for _, pattern := range conf.excludes {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("cannot interpret --exclude %q: %w", pattern, err)
+		}
+	}
+
+	if conf.maxDepth < 0 {
+		return errors.New("--depth should not be negative")
+	}
+
+	if conf.concurrency < 1 {
+		return errors.New("--concurrency should be at least 1")
+	}
+
+	return nil
+}
+
+type dirWalk struct {
+	// stat is updated atomically, and it has to be 64-bit aligned on 32-bit
+	// platforms, therefore it comes first
+	stat DirStat
+	*DirWalker
+	root  string
+	dev   uint64
+	sem   chan struct{}
+	wg    sync.WaitGroup
+	errMu sync.Mutex
+}
+
+// Walk measures a directory tree
+func (conf *DirWalker) Walk(root string) (*DirStat, error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", root)
+	}
+
+	walk := &dirWalk{
+		DirWalker: conf,
+		root:      root,
+		sem:       make(chan struct{}, conf.concurrency-1),
+	}
+	walk.dev, _ = deviceID(info)
+
+	walk.readDir(root, 0)
+	walk.wg.Wait()
+
+	return &walk.stat, nil
+}
+
+func (w *dirWalk) readDir(path string, depth int) {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		w.addError(err)
+		return
+	}
+
+	for _, entry := range entries {
+		fullpath := filepath.Join(path, entry.Name())
+
+		if w.excluded(fullpath, entry.Name()) {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				w.addError(err)
+			}
+
+			continue
+		}
+
+		if !info.IsDir() {
+			atomic.AddUint64(&w.stat.Files, 1)
+			atomic.AddUint64(&w.stat.Bytes, uint64(info.Size()))
+
+			continue
+		}
+
+		if dev, ok := deviceID(info); w.oneFS && ok && dev != w.dev {
+			continue
+		}
+
+		atomic.AddUint64(&w.stat.Dirs, 1)
+
+		if w.maxDepth > 0 && depth+1 >= w.maxDepth {
+			continue
+		}
+
+		select {
+		case w.sem <- struct{}{}:
+			w.wg.Add(1)
+
+			go func(path string) {
+				defer w.wg.Done()
+				w.readDir(path, depth+1)
+				<-w.sem
+			}(fullpath)
+		default:
+			w.readDir(fullpath, depth+1)
+		}
+	}
+}
+
+func (w *dirWalk) excluded(path, name string) bool {
+	rel, err := filepath.Rel(w.root, path)
+	if err != nil {
+		rel = path
+	}
+
+	for _, pattern := range w.excludes {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+
+		if ok, _ := filepath.Match(pattern, rel); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (w *dirWalk) addError(err error) {
+	w.errMu.Lock()
+	defer w.errMu.Unlock()
+
+	w.stat.Errors = append(w.stat.Errors, err)
+}
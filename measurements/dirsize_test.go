@@ -0,0 +1,88 @@
+package measurements
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func makeTree(t *testing.T) string {
+	t.Helper()
+
+	root := t.TempDir()
+	files := map[string]int{
+		"a.log":             10,
+		"b.tmp":             20,
+		"sub/c.log":         30,
+		"sub/deep/d.log":    40,
+		"sub/deep/e.tmp":    50,
+		"other/f.log":       60,
+		"other/deep/g.data": 70,
+	}
+
+	for name, size := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path, make([]byte, size), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return root
+}
+
+func TestDirWalker_Walk(t *testing.T) {
+	root := makeTree(t)
+
+	tests := []struct {
+		name   string
+		walker DirWalker
+		want   DirStat
+	}{
+		{
+			"full tree",
+			DirWalker{concurrency: 4},
+			DirStat{Bytes: 280, Files: 7, Dirs: 4},
+		},
+		{
+			"sequential",
+			DirWalker{concurrency: 1},
+			DirStat{Bytes: 280, Files: 7, Dirs: 4},
+		},
+		{
+			"depth limit",
+			DirWalker{concurrency: 4, maxDepth: 2},
+			DirStat{Bytes: 120, Files: 4, Dirs: 4},
+		},
+		{
+			"exclude by name",
+			DirWalker{concurrency: 4, excludes: []string{"*.tmp"}},
+			DirStat{Bytes: 210, Files: 5, Dirs: 4},
+		},
+		{
+			"exclude by relative path",
+			DirWalker{concurrency: 4, excludes: []string{"other/deep"}},
+			DirStat{Bytes: 210, Files: 6, Dirs: 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.walker.Check(); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := tt.walker.Walk(root)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got.Bytes != tt.want.Bytes || got.Files != tt.want.Files || got.Dirs != tt.want.Dirs {
+				t.Errorf("Walk() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package measurements
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/disk"
+	"github.com/spf13/pflag"
+)
+
+// DiskIORate contains I/O rates of a block device over a time period
+type DiskIORate struct {
+	ReadIOPS   float64
+	WriteIOPS  float64
+	ReadBytes  float64
+	WriteBytes float64
+	Await      time.Duration
+	Util       float64
+}
+
+// DiskIO samples block device I/O counters
+type DiskIO struct {
+	incdev    []string
+	excdev    []string
+	excreS    string
+	excre     *regexp.Regexp
+	intervalS string
+	interval  time.Duration
+	state     string
+	counters  func() (map[string]disk.IOCountersStat, error)
+}
+
+func (conf *DiskIO) SetFlags(flags *pflag.FlagSet) {
+	flags.StringSliceVarP(&conf.incdev, "incdev", "m", nil, "Include devices")
+	flags.StringSliceVarP(&conf.excdev, "excdev", "M", nil, "Ignore devices")
+	flags.StringVarP(&conf.excreS, "excre", "p", "^(loop|ram|zram)[0-9]+$", "Ignore device name regular expression")
+	flags.StringVarP(&conf.intervalS, "interval", "i", "1s", "Sampling interval")
+	flags.StringVarP(&conf.state, "state", "s", "", "State file to compare counters with previous run")
+}
+
+func (conf *DiskIO) Check() error {
+	var err error
+
+	if len(conf.excreS) > 0 {
+		conf.excre, err = regexp.Compile(conf.excreS)
+		if err != nil {
+			return fmt.Errorf("cannot interpret regexp from --excre: %w", err)
+		}
+	}
+
+	conf.interval, err = time.ParseDuration(conf.intervalS)
+	if err != nil {
+		return fmt.Errorf("cannot parse --interval: %w", err)
+	}
+
+	if conf.interval <= 0 {
+		return errors.New("--interval should be set")
+	}
+
+	if conf.counters == nil {
+		conf.counters = func() (map[string]disk.IOCountersStat, error) { return disk.IOCounters() }
+	}
+
+	return nil
+}
+
+// Selected returns true if a device is not explicitly excluded, or if it is explicitly included
+func (conf *DiskIO) Selected(name string) bool {
+	included := includes(name, conf.incdev)
+	excluded := includes(name, conf.excdev) ||
+		matchesPath(conf.excre, name)
+
+	return !excluded || included
+}
+
+// Rates returns I/O rates of selected devices. It compares current counters
+// with the ones saved in the state file if possible, otherwise it samples
+// counters over the sampling interval.
+func (conf *DiskIO) Rates() (map[string]*DiskIORate, error) {
+	var prev map[string]disk.IOCountersStat
+
+	prevTime := time.Time{}
+
+	if len(conf.state) > 0 {
+		// missing or unreadable state is replaced on save
+		prevTime, _ = LoadState(conf.state, &prev)
+	}
+
+	now := time.Now()
+
+	cur, err := conf.counters()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read I/O counters: %w", err)
+	}
+
+	if prev == nil || !prevTime.Before(now) {
+		prev, prevTime = cur, now
+
+		time.Sleep(conf.interval)
+
+		now = time.Now()
+
+		cur, err = conf.counters()
+		if err != nil {
+			return nil, fmt.Errorf("cannot read I/O counters: %w", err)
+		}
+	}
+
+	if len(conf.state) > 0 {
+		if err := SaveState(conf.state, now, cur); err != nil {
+			return nil, err
+		}
+	}
+
+	rates := map[string]*DiskIORate{}
+
+	for name, counters := range cur {
+		if !conf.Selected(name) {
+			continue
+		}
+
+		last, ok := prev[name]
+		if !ok {
+			continue
+		}
+
+		rates[name] = diskIORate(last, counters, now.Sub(prevTime))
+	}
+
+	return rates, nil
+}
+
+func diskIORate(prev, cur disk.IOCountersStat, elapsed time.Duration) *DiskIORate {
+	secs := elapsed.Seconds()
+	reads := delta(prev.ReadCount, cur.ReadCount)
+	writes := delta(prev.WriteCount, cur.WriteCount)
+	rate := &DiskIORate{
+		ReadIOPS:   float64(reads) / secs,
+		WriteIOPS:  float64(writes) / secs,
+		ReadBytes:  float64(delta(prev.ReadBytes, cur.ReadBytes)) / secs,
+		WriteBytes: float64(delta(prev.WriteBytes, cur.WriteBytes)) / secs,
+		Util:       float64(delta(prev.IoTime, cur.IoTime)) / (secs * 1000) * 100,
+	}
+
+	if reads+writes > 0 {
+		waited := delta(prev.ReadTime, cur.ReadTime) + delta(prev.WriteTime, cur.WriteTime)
+		rate.Await = time.Duration(waited) * time.Millisecond / time.Duration(reads+writes)
+	}
+
+	if rate.Util > 100 {
+		rate.Util = 100
+	}
+
+	return rate
+}
+
+// delta returns the increase of a counter, or 0 if it has been reset
+func delta(prev, cur uint64) uint64 {
+	if cur < prev {
+		return 0
+	}
+
+	return cur - prev
+}
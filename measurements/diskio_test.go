@@ -0,0 +1,87 @@
+package measurements
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-test/deep"
+	"github.com/shirou/gopsutil/v3/disk"
+)
+
+func TestDiskIORate(t *testing.T) {
+	prev := disk.IOCountersStat{
+		ReadCount:  100,
+		WriteCount: 200,
+		ReadBytes:  4096,
+		WriteBytes: 8192,
+		ReadTime:   1000,
+		WriteTime:  2000,
+		IoTime:     5000,
+	}
+	cur := disk.IOCountersStat{
+		ReadCount:  300,
+		WriteCount: 400,
+		ReadBytes:  2052096,
+		WriteBytes: 4104192,
+		ReadTime:   2000,
+		WriteTime:  5000,
+		IoTime:     6000,
+	}
+	want := &DiskIORate{
+		ReadIOPS:   100,
+		WriteIOPS:  100,
+		ReadBytes:  1024000,
+		WriteBytes: 2048000,
+		Await:      10 * time.Millisecond,
+		Util:       50,
+	}
+
+	if diff := deep.Equal(want, diskIORate(prev, cur, 2*time.Second)); diff != nil {
+		t.Error(diff)
+	}
+}
+
+func TestDiskIO_Rates(t *testing.T) {
+	state := filepath.Join(t.TempDir(), "diskio.json")
+	samples := []map[string]disk.IOCountersStat{
+		{"sda": {ReadCount: 10}, "loop0": {ReadCount: 10}},
+		{"sda": {ReadCount: 20}, "loop0": {ReadCount: 20}},
+		{"sda": {ReadCount: 40}, "loop0": {ReadCount: 40}},
+	}
+	conf := &DiskIO{
+		excreS:    "^loop[0-9]+$",
+		intervalS: "10ms",
+		state:     state,
+		counters: func() (map[string]disk.IOCountersStat, error) {
+			sample := samples[0]
+			samples = samples[1:]
+
+			return sample, nil
+		},
+	}
+
+	if err := conf.Check(); err != nil {
+		t.Fatal(err)
+	}
+
+	// first run samples twice, second run compares with state
+	for run := 0; run < 2; run++ {
+		rates, err := conf.Rates()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, ok := rates["loop0"]; ok {
+			t.Errorf("run %d: excluded device is measured", run)
+		}
+
+		if rate, ok := rates["sda"]; !ok || rate.ReadIOPS <= 0 {
+			t.Errorf("run %d: sda rate = %+v", run, rate)
+		}
+	}
+
+	if len(samples) != 0 {
+		t.Errorf("%d samples left unread", len(samples))
+	}
+}
@@ -0,0 +1,114 @@
+package measurements
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/hako/durafmt"
+	"github.com/julian7/sensulib"
+)
+
+// ErrNoFiles is returned when a glob pattern has no matches
+var ErrNoFiles = errors.New("not found")
+
+// FileConditions checks files' age, size, and content. Zero values are not
+// checked.
+type FileConditions struct {
+	MinAge  time.Duration
+	MaxAge  time.Duration
+	MinSize uint64
+	MaxSize uint64
+	Match   *regexp.Regexp
+}
+
+// GlobFiles returns files matching pattern, or ErrNoFiles if there are none
+func GlobFiles(pattern string) ([]string, error) {
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("cannot interpret %q: %w", pattern, err)
+	}
+
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("%s %w", pattern, ErrNoFiles)
+	}
+
+	return matches, nil
+}
+
+// Check returns an error if the file at path fails any of the conditions
+func (conds *FileConditions) Check(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("unable to read %s: %w", path, err)
+	}
+
+	age := time.Since(info.ModTime())
+
+	if conds.MaxAge > 0 && age > conds.MaxAge {
+		return fmt.Errorf("%s is too old, modified %s ago", path, HumanDuration(age))
+	}
+
+	if conds.MinAge > 0 && age < conds.MinAge {
+		return fmt.Errorf("%s is too new, modified %s ago", path, HumanDuration(age))
+	}
+
+	if conds.MinSize == 0 && conds.MaxSize == 0 && conds.Match == nil {
+		return nil
+	}
+
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("%s is not a regular file", path)
+	}
+
+	size := uint64(info.Size())
+
+	if size < conds.MinSize {
+		return fmt.Errorf(
+			"%s is too small: %s, expected at least %s",
+			path,
+			sensulib.SizeToHuman(size),
+			sensulib.SizeToHuman(conds.MinSize),
+		)
+	}
+
+	if conds.MaxSize > 0 && size > conds.MaxSize {
+		return fmt.Errorf(
+			"%s is too large: %s, expected at most %s",
+			path,
+			sensulib.SizeToHuman(size),
+			sensulib.SizeToHuman(conds.MaxSize),
+		)
+	}
+
+	if conds.Match != nil {
+		return conds.checkContent(path)
+	}
+
+	return nil
+}
+
+func (conds *FileConditions) checkContent(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("unable to read %s: %w", path, err)
+	}
+
+	defer file.Close()
+
+	if !conds.Match.MatchReader(bufio.NewReader(file)) {
+		return fmt.Errorf("%s doesn't match %q", path, conds.Match)
+	}
+
+	return nil
+}
+
+// HumanDuration returns a duration in human readable form, limited to its two
+// most significant units (like "2 days 3 hours")
+func HumanDuration(d time.Duration) string {
+	return durafmt.Parse(d).LimitFirstN(2).String()
+}
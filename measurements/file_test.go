@@ -0,0 +1,98 @@
+package measurements
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGlobFiles(t *testing.T) {
+	dir := t.TempDir()
+
+	for _, name := range []string{"a.log", "b.log", "c.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		pattern string
+		want    int
+		wantErr error
+	}{
+		{"single file", filepath.Join(dir, "c.txt"), 1, nil},
+		{"glob", filepath.Join(dir, "*.log"), 2, nil},
+		{"empty glob", filepath.Join(dir, "*.gz"), 0, ErrNoFiles},
+		{"missing file", filepath.Join(dir, "missing"), 0, ErrNoFiles},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GlobFiles(tt.pattern)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("GlobFiles() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if len(got) != tt.want {
+				t.Errorf("GlobFiles() = %v, want %d files", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFileConditions_Check(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "backup.log")
+
+	if err := os.WriteFile(file, []byte("backup finished successfully\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	modified := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(file, modified, modified); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		conds   FileConditions
+		wantErr string
+	}{
+		{"no conditions", file, FileConditions{}, ""},
+		{"missing file", filepath.Join(dir, "missing"), FileConditions{}, "unable to read"},
+		{"within max age", file, FileConditions{MaxAge: 3 * time.Hour}, ""},
+		{"too old", file, FileConditions{MaxAge: time.Hour}, "too old"},
+		{"within min age", file, FileConditions{MinAge: time.Hour}, ""},
+		{"too new", file, FileConditions{MinAge: 3 * time.Hour}, "too new"},
+		{"exact size", file, FileConditions{MinSize: 29, MaxSize: 29}, ""},
+		{"too small", file, FileConditions{MinSize: 30}, "too small"},
+		{"too large", file, FileConditions{MaxSize: 28}, "too large"},
+		{"matching", file, FileConditions{Match: regexp.MustCompile(`finished \w+`)}, ""},
+		{"not matching", file, FileConditions{Match: regexp.MustCompile(`failed`)}, "doesn't match"},
+		{"directory with size", dir, FileConditions{MinSize: 1}, "not a regular file"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.conds.Check(tt.path)
+
+			switch {
+			case len(tt.wantErr) == 0 && err != nil:
+				t.Errorf("FileConditions.Check() error = %v, wanted none", err)
+			case len(tt.wantErr) > 0 && err == nil:
+				t.Errorf("FileConditions.Check() error = nil, wanted %q", tt.wantErr)
+			case len(tt.wantErr) > 0 && !strings.Contains(err.Error(), tt.wantErr):
+				t.Errorf("FileConditions.Check() error = %v, wanted %q", err, tt.wantErr)
+			}
+		})
+	}
+}
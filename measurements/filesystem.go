@@ -3,6 +3,7 @@ package measurements
 import (
 	"fmt"
 	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/julian7/sensulib"
@@ -10,7 +11,15 @@ import (
 	"github.com/spf13/pflag"
 )
 
+// Dedup modes of Filesystem
+const (
+	DedupNone   = "none"
+	DedupDevice = "device"
+	DedupFSID   = "fsid"
+)
+
 type Filesystem struct {
+	dedup    string
 	inctype  []string
 	exctype  []string
 	incmnt   []string
@@ -18,6 +27,8 @@ type Filesystem struct {
 	excopt   []string
 	excpathS string
 	excpath  *regexp.Regexp
+	// fsid returns filesystem ID of a mount point; tests can replace it
+	fsid func(mountpoint string) string
 }
 
 func (conf *Filesystem) SetFlags(flags *pflag.FlagSet) {
@@ -27,11 +38,50 @@ func (conf *Filesystem) SetFlags(flags *pflag.FlagSet) {
 	flags.StringSliceVarP(&conf.excmnt, "excmnt", "M", nil, "Ignore mount points")
 	flags.StringVarP(&conf.excpathS, "excpath", "p", "", "Ignore path regular expression")
 	flags.StringSliceVarP(&conf.excopt, "excopt", "o", nil, "Ignore options")
+	flags.StringVar(&conf.dedup, "dedup", DedupNone, "Report filesystems mounted multiple times only once,"+
+		" grouped by source device or filesystem ID; one of none, device, fsid")
+}
+
+// Partition is a selected partition, with other mount points of the same
+// filesystem, if deduplication is requested
+type Partition struct {
+	disk.PartitionStat
+	Aliases []string
+}
+
+// Name returns the mount point, and the other mount points of the filesystem
+func (part *Partition) Name() string {
+	if len(part.Aliases) == 0 {
+		return part.Mountpoint
+	}
+
+	return fmt.Sprintf("%s (also on %s)", part.Mountpoint, strings.Join(part.Aliases, ", "))
+}
+
+// add registers another mount point of the filesystem, keeping the shortest
+// one as primary
+func (part *Partition) add(other *disk.PartitionStat) {
+	mountpoint := other.Mountpoint
+
+	if len(mountpoint) < len(part.Mountpoint) ||
+		(len(mountpoint) == len(part.Mountpoint) && mountpoint < part.Mountpoint) {
+		mountpoint = part.Mountpoint
+		part.PartitionStat = *other
+	}
+
+	part.Aliases = append(part.Aliases, mountpoint)
+	sort.Strings(part.Aliases)
 }
 
 func (conf *Filesystem) Check() error {
 	var err error
 
+	switch conf.dedup {
+	case DedupNone, DedupDevice, DedupFSID:
+	default:
+		return fmt.Errorf("--dedup should be one of %s, %s, or %s", DedupNone, DedupDevice, DedupFSID)
+	}
+
 	if len(conf.excpathS) > 0 {
 		conf.excpath, err = regexp.Compile(conf.excpathS)
 		if err != nil {
@@ -42,7 +92,68 @@ func (conf *Filesystem) Check() error {
 	return nil
 }
 
-func (conf *Filesystem) ForEach(cb func(*disk.PartitionStat)) error {
+// ForEach calls cb for every partition selected by filters, once for every
+// filesystem if deduplication is requested
+func (conf *Filesystem) ForEach(cb func(*Partition)) error {
+	var selected []*disk.PartitionStat
+
+	if err := conf.ForEachMounted(func(part *disk.PartitionStat) {
+		if conf.Selected(part) {
+			selected = append(selected, part)
+		}
+	}); err != nil {
+		return err
+	}
+
+	for _, part := range conf.group(selected) {
+		cb(part)
+	}
+
+	return nil
+}
+
+// group returns partitions grouped by their dedup keys, in mount order
+func (conf *Filesystem) group(selected []*disk.PartitionStat) []*Partition {
+	var parts []*Partition
+
+	groups := map[string]*Partition{}
+
+	for _, part := range selected {
+		key := conf.dedupKey(part)
+		if group, ok := groups[key]; ok && len(key) > 0 {
+			group.add(part)
+			continue
+		}
+
+		newPart := &Partition{PartitionStat: *part}
+		groups[key] = newPart
+		parts = append(parts, newPart)
+	}
+
+	return parts
+}
+
+func (conf *Filesystem) dedupKey(part *disk.PartitionStat) string {
+	switch conf.dedup {
+	case DedupDevice:
+		// pseudo filesystems (like tmpfs) share their "device" names
+		if directDevice(part.Device) {
+			return part.Device
+		}
+	case DedupFSID:
+		if conf.fsid != nil {
+			return conf.fsid(part.Mountpoint)
+		}
+
+		// btrfs reports different IDs for each subvolume
+		return filesystemID(part.Mountpoint)
+	}
+
+	return ""
+}
+
+// ForEachMounted calls cb for every mounted partition, regardless of filters
+func (conf *Filesystem) ForEachMounted(cb func(*disk.PartitionStat)) error {
 	parts, err := disk.Partitions(true)
 	if err != nil {
 		return sensulib.Unknown(fmt.Errorf("cannot read partitions: %w", err))
@@ -51,22 +162,25 @@ func (conf *Filesystem) ForEach(cb func(*disk.PartitionStat)) error {
 	for _, part := range parts {
 		part := part
 
-		included := includes(part.Fstype, conf.inctype) ||
-			includes(part.Mountpoint, conf.incmnt)
-		excluded := includes(part.Fstype, conf.exctype) ||
-			includes(part.Mountpoint, conf.excmnt) ||
-			hasOpt(conf.excopt, part.Opts) ||
-			matchesPath(conf.excpath, part.Mountpoint) ||
-			!directDevice(part.Device)
-
-		if !excluded || included {
-			cb(&part)
-		}
+		cb(&part)
 	}
 
 	return nil
 }
 
+// Selected returns true if a partition is not explicitly excluded, or if it is explicitly included
+func (conf *Filesystem) Selected(part *disk.PartitionStat) bool {
+	included := includes(part.Fstype, conf.inctype) ||
+		includes(part.Mountpoint, conf.incmnt)
+	excluded := includes(part.Fstype, conf.exctype) ||
+		includes(part.Mountpoint, conf.excmnt) ||
+		hasOpt(conf.excopt, part.Opts) ||
+		matchesPath(conf.excpath, part.Mountpoint) ||
+		!directDevice(part.Device)
+
+	return !excluded || included
+}
+
 func includes(needle string, haystack []string) bool {
 	if len(haystack) == 0 {
 		return false
@@ -0,0 +1,87 @@
+package measurements
+
+import (
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/shirou/gopsutil/v3/disk"
+)
+
+func TestPartition_add(t *testing.T) {
+	part := &Partition{PartitionStat: disk.PartitionStat{Device: "/dev/sda1", Mountpoint: "/var/lib/docker/x"}}
+
+	for _, mountpoint := range []string{"/srv", "/var/lib/docker/a", "/data"} {
+		part.add(&disk.PartitionStat{Device: "/dev/sda1", Mountpoint: mountpoint})
+	}
+
+	want := "/srv (also on /data, /var/lib/docker/a, /var/lib/docker/x)"
+	if got := part.Name(); got != want {
+		t.Errorf("Name() = %q, want %q", got, want)
+	}
+}
+
+func TestFilesystem_dedupKey(t *testing.T) {
+	tests := []struct {
+		name  string
+		dedup string
+		part  disk.PartitionStat
+		want  string
+	}{
+		{"none", DedupNone, disk.PartitionStat{Device: "/dev/sda1"}, ""},
+		{"device", DedupDevice, disk.PartitionStat{Device: "/dev/sda1"}, "/dev/sda1"},
+		{"zfs dataset", DedupDevice, disk.PartitionStat{Device: "tank/data"}, "tank/data"},
+		{"pseudo filesystem", DedupDevice, disk.PartitionStat{Device: "tmpfs"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := &Filesystem{dedup: tt.dedup}
+			if got := conf.dedupKey(&tt.part); got != tt.want {
+				t.Errorf("dedupKey() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilesystem_group(t *testing.T) {
+	// btrfs subvolumes share their device, but not their filesystem IDs;
+	// bind mounts share both
+	mounts := []*disk.PartitionStat{
+		{Device: "/dev/sda2", Mountpoint: "/", Fstype: "btrfs"},
+		{Device: "/dev/sda2", Mountpoint: "/home", Fstype: "btrfs"},
+		{Device: "/dev/sda2", Mountpoint: "/var/lib/docker/home", Fstype: "btrfs"},
+		{Device: "tmpfs", Mountpoint: "/tmp", Fstype: "tmpfs"},
+		{Device: "tmpfs", Mountpoint: "/run", Fstype: "tmpfs"},
+	}
+	fsids := map[string]string{
+		"/":                    "0000000100000005",
+		"/home":                "0000000100000100",
+		"/var/lib/docker/home": "0000000100000100",
+		"/tmp":                 "0000000000000001",
+		"/run":                 "0000000000000002",
+	}
+	tests := []struct {
+		dedup string
+		want  []string
+	}{
+		{DedupNone, []string{"/", "/home", "/var/lib/docker/home", "/tmp", "/run"}},
+		{DedupDevice, []string{"/ (also on /home, /var/lib/docker/home)", "/tmp", "/run"}},
+		{DedupFSID, []string{"/", "/home (also on /var/lib/docker/home)", "/tmp", "/run"}},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.dedup, func(t *testing.T) {
+			conf := &Filesystem{dedup: tt.dedup, fsid: func(mountpoint string) string { return fsids[mountpoint] }}
+
+			var got []string
+			for _, part := range conf.group(mounts) {
+				got = append(got, part.Name())
+			}
+
+			if diff := deep.Equal(got, tt.want); diff != nil {
+				t.Error(diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package measurements
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HeaderAssertion checks presence, absence, or value of an HTTP header
+type HeaderAssertion struct {
+	Source   string
+	Name     string
+	Absent   bool
+	HasValue bool
+	Value    string
+	Match    *regexp.Regexp
+}
+
+// ParseHeaderAssertion parses header assertions in one of these forms:
+//
+//	NAME         header is present
+//	!NAME        header is absent
+//	NAME=VALUE   header has VALUE, which can be empty
+//	NAME~=REGEX  header matches REGEX
+func ParseHeaderAssertion(source string) (*HeaderAssertion, error) {
+	assertion := &HeaderAssertion{Source: source}
+	text := strings.TrimSpace(source)
+
+	switch idx := strings.Index(text, "="); {
+	case strings.HasPrefix(text, "!"):
+		assertion.Absent = true
+		assertion.Name = strings.TrimSpace(text[1:])
+	case idx < 0:
+		assertion.Name = text
+	case idx > 0 && text[idx-1] == '~':
+		var err error
+
+		assertion.Name = strings.TrimSpace(text[:idx-1])
+
+		assertion.Match, err = regexp.Compile(strings.TrimSpace(text[idx+1:]))
+		if err != nil {
+			return nil, fmt.Errorf("cannot compile regexp: %w", err)
+		}
+	default:
+		assertion.Name = strings.TrimSpace(text[:idx])
+		assertion.HasValue = true
+		assertion.Value = strings.TrimSpace(text[idx+1:])
+	}
+
+	if len(assertion.Name) == 0 || strings.ContainsAny(assertion.Name, " \t=!~") {
+		return nil, errors.New("invalid header name")
+	}
+
+	assertion.Name = http.CanonicalHeaderKey(assertion.Name)
+
+	return assertion, nil
+}
+
+// Check returns an error if the assertion doesn't hold for headers. Headers
+// with multiple values pass if any of the values matches.
+func (assertion *HeaderAssertion) Check(headers http.Header) error {
+	values := headers.Values(assertion.Name)
+
+	switch {
+	case assertion.Absent:
+		if len(values) > 0 {
+			return fmt.Errorf("header %s is present", assertion.Name)
+		}
+
+		return nil
+	case len(values) == 0:
+		return fmt.Errorf("header %s is missing", assertion.Name)
+	case assertion.Match != nil:
+		for _, value := range values {
+			if assertion.Match.MatchString(value) {
+				return nil
+			}
+		}
+
+		return fmt.Errorf("header %s is %q, expected to match %q", assertion.Name, values[0], assertion.Match)
+	case assertion.HasValue:
+		for _, value := range values {
+			if value == assertion.Value {
+				return nil
+			}
+		}
+
+		return fmt.Errorf("header %s is %q, expected %q", assertion.Name, values[0], assertion.Value)
+	}
+
+	return nil
+}
+
+// AuditSecurityHeaders returns security issues of response headers: missing
+// or short HSTS (on HTTPS only), missing Content-Security-Policy, missing
+// X-Content-Type-Options: nosniff, and cookies without Secure (on HTTPS
+// only) or HttpOnly flags.
+func AuditSecurityHeaders(resp *http.Response, minHSTS time.Duration) []string {
+	var issues []string
+
+	secure := resp.Request != nil && resp.Request.URL.Scheme == "https"
+
+	if secure {
+		if issue := auditHSTS(resp.Header.Get("Strict-Transport-Security"), minHSTS); len(issue) > 0 {
+			issues = append(issues, issue)
+		}
+	}
+
+	if len(resp.Header.Get("Content-Security-Policy")) == 0 {
+		issues = append(issues, "Content-Security-Policy is missing")
+	}
+
+	if !strings.EqualFold(strings.TrimSpace(resp.Header.Get("X-Content-Type-Options")), "nosniff") {
+		issues = append(issues, "X-Content-Type-Options is not nosniff")
+	}
+
+	for _, cookie := range resp.Cookies() {
+		var flags []string
+
+		if secure && !cookie.Secure {
+			flags = append(flags, "Secure")
+		}
+
+		if !cookie.HttpOnly {
+			flags = append(flags, "HttpOnly")
+		}
+
+		if len(flags) > 0 {
+			issues = append(issues, fmt.Sprintf("cookie %s has no %s flag", cookie.Name, strings.Join(flags, ", ")))
+		}
+	}
+
+	return issues
+}
+
+func auditHSTS(header string, minAge time.Duration) string {
+	if len(header) == 0 {
+		return "Strict-Transport-Security is missing"
+	}
+
+	for _, directive := range strings.Split(header, ";") {
+		items := strings.SplitN(strings.TrimSpace(directive), "=", 2)
+		if len(items) != 2 || !strings.EqualFold(items[0], "max-age") {
+			continue
+		}
+
+		age, err := strconv.ParseInt(strings.Trim(items[1], `"`), 10, 64)
+		if err != nil {
+			return fmt.Sprintf("Strict-Transport-Security has invalid max-age %q", items[1])
+		}
+
+		// large max-age values would overflow time.Duration
+		if age < int64(minAge/time.Second) {
+			return fmt.Sprintf("Strict-Transport-Security max-age is %ds, expected at least %.0fs", age, minAge.Seconds())
+		}
+
+		return ""
+	}
+
+	return "Strict-Transport-Security has no max-age"
+}
@@ -0,0 +1,135 @@
+package measurements
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/go-test/deep"
+)
+
+func TestHeaderAssertion_Check(t *testing.T) {
+	headers := http.Header{}
+	headers.Set("Cache-Control", "no-store, max-age=0")
+	headers.Set("X-Frame-Options", "DENY")
+	headers.Add("Vary", "Accept-Encoding")
+	headers.Add("Vary", "Origin")
+	headers.Set("X-Robots-Tag", "")
+
+	tests := []struct {
+		source   string
+		wantErr  bool
+		parseErr bool
+	}{
+		{"Cache-Control", false, false},
+		{"cache-control~=no-store", false, false},
+		{"Cache-Control~=^private", true, false},
+		{"X-Frame-Options=DENY", false, false},
+		{"X-Frame-Options=SAMEORIGIN", true, false},
+		{"Vary=Origin", false, false},
+		{"X-Robots-Tag=", false, false},
+		{"X-Frame-Options=", true, false},
+		{"!Server", false, false},
+		{"!X-Frame-Options", true, false},
+		{"Pragma", true, false},
+		{"=value", false, true},
+		{"Cache-Control~=(", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			assertion, err := ParseHeaderAssertion(tt.source)
+			if (err != nil) != tt.parseErr {
+				t.Fatalf("ParseHeaderAssertion() error = %v, wantErr %v", err, tt.parseErr)
+			}
+
+			if err != nil {
+				return
+			}
+
+			if err := assertion.Check(headers); (err != nil) != tt.wantErr {
+				t.Errorf("Check() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAuditSecurityHeaders(t *testing.T) {
+	tests := []struct {
+		name    string
+		scheme  string
+		headers map[string][]string
+		want    []string
+	}{
+		{
+			"secure",
+			"https",
+			map[string][]string{
+				"Strict-Transport-Security": {"max-age=31536000; includeSubDomains"},
+				"Content-Security-Policy":   {"default-src 'self'"},
+				"X-Content-Type-Options":    {"nosniff"},
+				"Set-Cookie":                {"session=abc; Path=/; Secure; HttpOnly"},
+			},
+			nil,
+		},
+		{
+			"HSTS max-age beyond duration range",
+			"https",
+			map[string][]string{
+				"Strict-Transport-Security": {"max-age=9223372036854775807"},
+				"Content-Security-Policy":   {"default-src 'self'"},
+				"X-Content-Type-Options":    {"nosniff"},
+			},
+			nil,
+		},
+		{
+			"nothing set",
+			"https",
+			map[string][]string{},
+			[]string{
+				"Strict-Transport-Security is missing",
+				"Content-Security-Policy is missing",
+				"X-Content-Type-Options is not nosniff",
+			},
+		},
+		{
+			"short HSTS and insecure cookies",
+			"https",
+			map[string][]string{
+				"Strict-Transport-Security": {"max-age=3600"},
+				"Content-Security-Policy":   {"default-src 'self'"},
+				"X-Content-Type-Options":    {"nosniff"},
+				"Set-Cookie":                {"session=abc; Path=/", "lang=en; Secure"},
+			},
+			[]string{
+				"Strict-Transport-Security max-age is 3600s, expected at least 15552000s",
+				"cookie session has no Secure, HttpOnly flag",
+				"cookie lang has no HttpOnly flag",
+			},
+		},
+		{
+			"plain HTTP",
+			"http",
+			map[string][]string{
+				"Content-Security-Policy": {"default-src 'self'"},
+				"X-Content-Type-Options":  {"nosniff"},
+				"Set-Cookie":              {"session=abc; Path=/; HttpOnly"},
+			},
+			nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{
+				Header:  http.Header(tt.headers),
+				Request: &http.Request{URL: &url.URL{Scheme: tt.scheme, Host: "example.com"}},
+			}
+
+			if diff := deep.Equal(AuditSecurityHeaders(resp, 180*24*time.Hour), tt.want); diff != nil {
+				t.Error(diff)
+			}
+		})
+	}
+}
@@ -6,12 +6,17 @@ import (
 	"time"
 )
 
+// HTTPTracer records timings of the last request. Phases not happening in
+// the last request (like name resolution, connection, or TLS handshake on a
+// reused connection) are not measured, and their durations are 0.
 type HTTPTracer struct {
 	Trace             *httptrace.ClientTrace
+	GetConn           time.Time
 	DNSStart          time.Time
 	ConnStart         time.Time
 	ConnDone          time.Time
 	GotConn           time.Time
+	Reused            bool
 	StartResponding   time.Time
 	TLSHandshakeStart time.Time
 	TLSHandshakeDone  time.Time
@@ -21,6 +26,11 @@ type HTTPTracer struct {
 func NewHTTPTracer() *HTTPTracer {
 	tracer := &HTTPTracer{}
 	trace := &httptrace.ClientTrace{
+		// each request of a redirect chain starts from scratch, therefore
+		// timings describe the final request
+		GetConn: func(_ string) {
+			*tracer = HTTPTracer{Trace: tracer.Trace, GetConn: time.Now()}
+		},
 		DNSStart: func(_ httptrace.DNSStartInfo) { tracer.DNSStart = time.Now() },
 		DNSDone:  func(_ httptrace.DNSDoneInfo) { tracer.ConnStart = time.Now() },
 		ConnectStart: func(_, _ string) {
@@ -28,8 +38,11 @@ func NewHTTPTracer() *HTTPTracer {
 				tracer.ConnStart = time.Now()
 			}
 		},
-		ConnectDone:          func(_, _ string, _ error) { tracer.ConnDone = time.Now() },
-		GotConn:              func(_ httptrace.GotConnInfo) { tracer.GotConn = time.Now() },
+		ConnectDone: func(_, _ string, _ error) { tracer.ConnDone = time.Now() },
+		GotConn: func(info httptrace.GotConnInfo) {
+			tracer.GotConn = time.Now()
+			tracer.Reused = info.Reused
+		},
 		GotFirstResponseByte: func() { tracer.StartResponding = time.Now() },
 		TLSHandshakeStart:    func() { tracer.TLSHandshakeStart = time.Now() },
 		TLSHandshakeDone:     func(_ tls.ConnectionState, _ error) { tracer.TLSHandshakeDone = time.Now() },
@@ -46,22 +59,72 @@ func (t *HTTPTracer) Done() {
 	}
 }
 
+// between returns time elapsed between start and end, or 0 if any of them
+// is not measured
+func between(start, end time.Time) time.Duration {
+	if start.IsZero() || end.IsZero() {
+		return 0
+	}
+
+	return end.Sub(start)
+}
+
+// origin returns the start of name resolution, or the start of getting a
+// connection if there was no name resolution (like for IP addresses, or for
+// reused connections)
+func (t *HTTPTracer) origin() time.Time {
+	if !t.DNSStart.IsZero() {
+		return t.DNSStart
+	}
+
+	return t.GetConn
+}
+
 func (t *HTTPTracer) Total() time.Duration {
-	return t.Finished.Sub(t.DNSStart)
+	return between(t.origin(), t.Finished)
 }
 
 func (t *HTTPTracer) Namelookup() time.Duration {
-	return t.ConnStart.Sub(t.DNSStart)
+	return between(t.DNSStart, t.ConnStart)
 }
 
 func (t *HTTPTracer) Connect() time.Duration {
-	return t.GotConn.Sub(t.DNSStart)
+	return between(t.origin(), t.GotConn)
 }
 
 func (t *HTTPTracer) Pretransfer() time.Duration {
-	return t.TLSHandshakeDone.Sub(t.DNSStart)
+	return between(t.origin(), t.TLSHandshakeDone)
 }
 
 func (t *HTTPTracer) Starttransfer() time.Duration {
-	return t.StartResponding.Sub(t.DNSStart)
+	return between(t.origin(), t.StartResponding)
+}
+
+// DNSTime returns time spent on name resolution
+func (t *HTTPTracer) DNSTime() time.Duration {
+	return t.Namelookup()
+}
+
+// ConnectTime returns time spent on establishing TCP connection
+func (t *HTTPTracer) ConnectTime() time.Duration {
+	if t.Reused {
+		return 0
+	}
+
+	return between(t.ConnStart, t.ConnDone)
+}
+
+// TLSTime returns time spent on TLS handshake
+func (t *HTTPTracer) TLSTime() time.Duration {
+	if t.Reused {
+		return 0
+	}
+
+	return between(t.TLSHandshakeStart, t.TLSHandshakeDone)
+}
+
+// FirstByteTime returns time spent waiting for the first byte of the
+// response, after the connection is established
+func (t *HTTPTracer) FirstByteTime() time.Duration {
+	return between(t.GotConn, t.StartResponding)
 }
@@ -0,0 +1,106 @@
+package measurements
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"strings"
+	"testing"
+	"time"
+)
+
+func tracedGet(t *testing.T, client *http.Client, url string) *HTTPTracer {
+	t.Helper()
+
+	tracer := NewHTTPTracer()
+
+	req, err := http.NewRequestWithContext(httptrace.WithClientTrace(context.Background(), tracer.Trace), http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+	tracer.Done()
+
+	return tracer
+}
+
+func TestHTTPTracer(t *testing.T) {
+	server := httptest.NewTLSServer(redirectServer("/final"))
+	defer server.Close()
+
+	// a host name makes name resolution happen
+	base := strings.Replace(server.URL, "127.0.0.1", "localhost", 1)
+	client := server.Client()
+	client.Transport.(*http.Transport).TLSClientConfig.ServerName = "example.com"
+
+	fresh := tracedGet(t, client, base+"/final")
+	reused := tracedGet(t, client, base+"/final")
+	redirected := tracedGet(t, client, base+"/hop1")
+
+	tests := []struct {
+		name   string
+		tracer *HTTPTracer
+		reused bool
+	}{
+		{"fresh connection", fresh, false},
+		{"reused connection", reused, true},
+		{"redirect on the same connection", redirected, true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.tracer.Reused != tt.reused {
+				t.Errorf("Reused = %v, want %v", tt.tracer.Reused, tt.reused)
+			}
+
+			phases := map[string]time.Duration{
+				"dns":     tt.tracer.DNSTime(),
+				"connect": tt.tracer.ConnectTime(),
+				"tls":     tt.tracer.TLSTime(),
+			}
+
+			for name, got := range phases {
+				switch {
+				case tt.reused && got != 0:
+					t.Errorf("%s = %s, want 0", name, got)
+				case !tt.reused && got <= 0:
+					t.Errorf("%s = %s, want positive", name, got)
+				}
+			}
+
+			// not measured phases shouldn't result in negative or epoch-sized durations
+			durations := map[string]time.Duration{
+				"total":         tt.tracer.Total(),
+				"connect":       tt.tracer.Connect(),
+				"starttransfer": tt.tracer.Starttransfer(),
+				"ttfb":          tt.tracer.FirstByteTime(),
+			}
+
+			for name, got := range durations {
+				if got <= 0 || got > time.Minute {
+					t.Errorf("%s = %s, want positive and short", name, got)
+				}
+			}
+
+			if total := tt.tracer.Total(); tt.tracer.Starttransfer() > total {
+				t.Errorf("starttransfer = %s, longer than total %s", tt.tracer.Starttransfer(), total)
+			}
+		})
+	}
+
+	// on fresh connections, timings are measured from the start of name resolution
+	if got, want := fresh.Total(), fresh.Finished.Sub(fresh.DNSStart); got != want {
+		t.Errorf("Total() = %s, want %s", got, want)
+	}
+}
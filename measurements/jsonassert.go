@@ -0,0 +1,379 @@
+package measurements
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/jmespath/go-jmespath"
+	"github.com/julian7/sensulib"
+)
+
+var jsonOperators = []string{"==", "!=", "<=", ">=", "<", ">"}
+
+// JSONAssertion compares the result of a JMESPath expression to a value, or
+// to numeric warning and critical thresholds
+type JSONAssertion struct {
+	Source   string
+	Expr     string
+	Operator string
+	Value    interface{}
+	Warn     bool
+	WarnAt   *JSONThreshold
+	CritAt   *JSONThreshold
+	query    *jmespath.JMESPath
+}
+
+// JSONThreshold is a numeric alert condition, like `>1000`
+type JSONThreshold struct {
+	Operator string
+	Value    float64
+}
+
+// ParseJSONAssertion parses assertions in `[warn:|crit:]EXPR OP VALUE` form,
+// where EXPR is a JMESPath expression, OP is a comparison operator, and VALUE
+// is a JSON value. VALUE is taken as a string if it is not valid JSON.
+//
+// Numeric results can be checked against thresholds in
+// `EXPR [warn:OPNUMBER] [crit:OPNUMBER]` form, like
+// `queue.depth warn:>1000 crit:>5000`, where thresholds are alert conditions.
+func ParseJSONAssertion(source string) (*JSONAssertion, error) {
+	assertion := &JSONAssertion{Source: source}
+
+	text, err := assertion.parseThresholds(strings.TrimSpace(source))
+	if err != nil {
+		return nil, err
+	}
+
+	if assertion.WarnAt != nil || assertion.CritAt != nil {
+		assertion.Expr = text
+
+		return assertion.compile()
+	}
+
+	switch {
+	case strings.HasPrefix(text, "warn:"):
+		assertion.Warn = true
+		text = text[len("warn:"):]
+	case strings.HasPrefix(text, "crit:"):
+		text = text[len("crit:"):]
+	}
+
+	pos, op := lastOperator(text)
+	if pos < 0 {
+		return nil, errors.New("no comparison operator found")
+	}
+
+	assertion.Expr = strings.TrimSpace(text[:pos])
+	assertion.Operator = op
+	value := strings.TrimSpace(text[pos+len(op):])
+
+	if len(assertion.Expr) == 0 {
+		return nil, errors.New("no expression provided")
+	}
+
+	if len(value) == 0 {
+		return nil, errors.New("no value provided")
+	}
+
+	if err := json.Unmarshal([]byte(value), &assertion.Value); err != nil {
+		assertion.Value = value
+	}
+
+	return assertion.compile()
+}
+
+// parseThresholds removes trailing `warn:` and `crit:` thresholds from text,
+// and returns the rest
+func (assertion *JSONAssertion) parseThresholds(text string) (string, error) {
+	for {
+		pos := strings.LastIndexAny(text, " \t")
+		field := text[pos+1:]
+
+		var target **JSONThreshold
+
+		switch {
+		case strings.HasPrefix(field, "warn:"):
+			target = &assertion.WarnAt
+		case strings.HasPrefix(field, "crit:"):
+			target = &assertion.CritAt
+		}
+
+		if target == nil || !startsWithOperator(field[len("warn:"):]) {
+			return text, nil
+		}
+
+		if pos < 0 {
+			return "", errors.New("no expression provided")
+		}
+
+		if *target != nil {
+			return "", fmt.Errorf("%s threshold is set twice", field[:len("warn")])
+		}
+
+		threshold, err := parseJSONThreshold(field[len("warn:"):])
+		if err != nil {
+			return "", fmt.Errorf("%s threshold: %w", field[:len("warn")], err)
+		}
+
+		*target = threshold
+		text = strings.TrimSpace(text[:pos])
+	}
+}
+
+func startsWithOperator(text string) bool {
+	for _, op := range jsonOperators {
+		if strings.HasPrefix(text, op) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func parseJSONThreshold(text string) (*JSONThreshold, error) {
+	for _, op := range jsonOperators {
+		if !strings.HasPrefix(text, op) {
+			continue
+		}
+
+		value, err := strconv.ParseFloat(text[len(op):], 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a number", text[len(op):])
+		}
+
+		return &JSONThreshold{Operator: op, Value: value}, nil
+	}
+
+	return nil, errors.New("no comparison operator found")
+}
+
+func (assertion *JSONAssertion) compile() (*JSONAssertion, error) {
+	var err error
+
+	assertion.query, err = jmespath.Compile(assertion.Expr)
+	if err != nil {
+		return nil, fmt.Errorf("cannot compile %q: %w", assertion.Expr, err)
+	}
+
+	return assertion, nil
+}
+
+// String returns the threshold in its original form
+func (threshold *JSONThreshold) String() string {
+	return threshold.Operator + strconv.FormatFloat(threshold.Value, 'f', -1, 64)
+}
+
+// lastOperator returns the position of the last comparison operator, which
+// is not in a quoted string or in brackets
+func lastOperator(text string) (int, string) {
+	var quote byte
+
+	depth := 0
+	pos := -1
+	found := ""
+
+	for i := 0; i < len(text); i++ {
+		chr := text[i]
+
+		if quote != 0 {
+			switch chr {
+			case '\\':
+				i++
+			case quote:
+				quote = 0
+			}
+
+			continue
+		}
+
+		switch chr {
+		case '\'', '"', '`':
+			quote = chr
+			continue
+		case '[', '(', '{':
+			depth++
+			continue
+		case ']', ')', '}':
+			depth--
+			continue
+		}
+
+		if depth != 0 {
+			continue
+		}
+
+		for _, op := range jsonOperators {
+			if strings.HasPrefix(text[i:], op) {
+				pos, found = i, op
+				i += len(op) - 1
+
+				break
+			}
+		}
+	}
+
+	return pos, found
+}
+
+// Evaluate returns whether the assertion holds on data, and the result of
+// the expression. Assertions with thresholds hold if no threshold is crossed.
+func (assertion *JSONAssertion) Evaluate(data interface{}) (bool, interface{}, error) {
+	result, err := assertion.search(data)
+	if err != nil {
+		return false, nil, err
+	}
+
+	if assertion.WarnAt != nil || assertion.CritAt != nil {
+		crossed, err := assertion.crossed(result)
+		if err != nil {
+			return false, result, err
+		}
+
+		return crossed == nil, result, nil
+	}
+
+	ok, err := compareJSON(result, assertion.Operator, assertion.Value)
+	if err != nil {
+		return false, result, fmt.Errorf("%s: %w", strings.TrimSpace(assertion.Source), err)
+	}
+
+	return ok, result, nil
+}
+
+func (assertion *JSONAssertion) search(data interface{}) (interface{}, error) {
+	result, err := assertion.query.Search(data)
+	if err != nil {
+		return nil, fmt.Errorf("searching for %q in body JSON: %w", assertion.Expr, err)
+	}
+
+	return result, nil
+}
+
+// crossed returns the most severe threshold crossed by result
+func (assertion *JSONAssertion) crossed(result interface{}) (*JSONThreshold, error) {
+	if _, ok := result.(float64); !ok {
+		return nil, fmt.Errorf("%s is %s, which is not a number", assertion.Expr, jsonString(result))
+	}
+
+	for _, threshold := range []*JSONThreshold{assertion.CritAt, assertion.WarnAt} {
+		if threshold == nil {
+			continue
+		}
+
+		alert, err := compareJSON(result, threshold.Operator, threshold.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		if alert {
+			return threshold, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// Check evaluates the assertion on data, returning a warning or critical
+// error if it doesn't hold
+func (assertion *JSONAssertion) Check(data interface{}) *sensulib.Error {
+	if assertion.WarnAt != nil || assertion.CritAt != nil {
+		return assertion.checkThresholds(data)
+	}
+
+	ok, result, err := assertion.Evaluate(data)
+	if err != nil {
+		return sensulib.Crit(err)
+	}
+
+	if ok {
+		return nil
+	}
+
+	err = fmt.Errorf(
+		"%s is %s, expected %s %s",
+		assertion.Expr,
+		jsonString(result),
+		assertion.Operator,
+		jsonString(assertion.Value),
+	)
+
+	if assertion.Warn {
+		return sensulib.Warn(err)
+	}
+
+	return sensulib.Crit(err)
+}
+
+// checkThresholds evaluates the expression once, and returns an error of the
+// most severe threshold crossed
+func (assertion *JSONAssertion) checkThresholds(data interface{}) *sensulib.Error {
+	result, err := assertion.search(data)
+	if err != nil {
+		return sensulib.Crit(err)
+	}
+
+	threshold, err := assertion.crossed(result)
+	if err != nil {
+		return sensulib.Crit(err)
+	}
+
+	if threshold == nil {
+		return nil
+	}
+
+	err = fmt.Errorf("%s is %s, limit is %s", assertion.Expr, jsonString(result), threshold)
+
+	if threshold == assertion.CritAt {
+		return sensulib.Crit(err)
+	}
+
+	return sensulib.Warn(err)
+}
+
+func compareJSON(result interface{}, op string, value interface{}) (bool, error) {
+	got, gotNum := result.(float64)
+	want, wantNum := value.(float64)
+
+	if gotNum && wantNum {
+		switch op {
+		case "==":
+			return got == want, nil
+		case "!=":
+			return got != want, nil
+		case "<":
+			return got < want, nil
+		case "<=":
+			return got <= want, nil
+		case ">":
+			return got > want, nil
+		case ">=":
+			return got >= want, nil
+		}
+	}
+
+	switch op {
+	case "==":
+		return reflect.DeepEqual(result, value), nil
+	case "!=":
+		return !reflect.DeepEqual(result, value), nil
+	}
+
+	if !gotNum {
+		return false, fmt.Errorf("%s is not a number", jsonString(result))
+	}
+
+	return false, fmt.Errorf("%s is not a number", jsonString(value))
+}
+
+func jsonString(value interface{}) string {
+	out, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+
+	return string(out)
+}
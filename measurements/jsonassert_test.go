@@ -0,0 +1,181 @@
+package measurements
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/julian7/sensulib"
+)
+
+func TestParseJSONAssertion(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		want    *JSONAssertion
+		wantErr bool
+	}{
+		{
+			"string",
+			`status == "ok"`,
+			&JSONAssertion{Expr: "status", Operator: "==", Value: "ok"},
+			false,
+		},
+		{
+			"bare string",
+			`status != degraded`,
+			&JSONAssertion{Expr: "status", Operator: "!=", Value: "degraded"},
+			false,
+		},
+		{
+			"warning",
+			"warn:queue.depth < 1000",
+			&JSONAssertion{Expr: "queue.depth", Operator: "<", Value: 1000.0, Warn: true},
+			false,
+		},
+		{
+			"function",
+			"crit: length(nodes) >= 3",
+			&JSONAssertion{Expr: "length(nodes)", Operator: ">=", Value: 3.0},
+			false,
+		},
+		{
+			"filter with comparison",
+			"replicas[?state!='up'] | length(@) == 0",
+			&JSONAssertion{Expr: "replicas[?state!='up'] | length(@)", Operator: "==", Value: 0.0},
+			false,
+		},
+		{
+			"thresholds",
+			"queue.depth warn:>1000 crit:>=5000",
+			&JSONAssertion{
+				Expr:   "queue.depth",
+				WarnAt: &JSONThreshold{Operator: ">", Value: 1000},
+				CritAt: &JSONThreshold{Operator: ">=", Value: 5000},
+			},
+			false,
+		},
+		{
+			"critical threshold only",
+			"length(nodes) crit:<2",
+			&JSONAssertion{Expr: "length(nodes)", CritAt: &JSONThreshold{Operator: "<", Value: 2}},
+			false,
+		},
+		{"no operator", "status", nil, true},
+		{"threshold without expression", "warn:>1000", nil, true},
+		{"threshold set twice", "queue.depth warn:>1 warn:>2", nil, true},
+		{"non-numeric threshold", "queue.depth crit:>high", nil, true},
+		{"no expression", "== 1", nil, true},
+		{"no value", "status ==", nil, true},
+		{"operator in filter only", "replicas[?state!='up']", nil, true},
+		{"invalid expression", "status[ == 1", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseJSONAssertion(tt.source)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseJSONAssertion() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if err != nil {
+				return
+			}
+
+			got.Source = ""
+			got.query = nil
+
+			if diff := deep.Equal(got, tt.want); diff != nil {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestJSONAssertion_Evaluate(t *testing.T) {
+	var data interface{}
+
+	body := `{
+		"status": "ok",
+		"queue": {"depth": 1200},
+		"nodes": ["a", "b", "c"],
+		"replicas": [{"state": "up"}, {"state": "down"}]
+	}`
+	if err := json.Unmarshal([]byte(body), &data); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		source  string
+		want    bool
+		wantErr bool
+	}{
+		{`status == "ok"`, true, false},
+		{`status != "ok"`, false, false},
+		{"queue.depth < 1000", false, false},
+		{"queue.depth <= 5000", true, false},
+		{"length(nodes) >= 3", true, false},
+		{"replicas[?state!='up'] | length(@) == 0", false, false},
+		{"missing == null", true, false},
+		{"status > 3", false, true},
+		{"queue.depth > high", false, true},
+		{"queue.depth warn:>1000 crit:>5000", false, false},
+		{"queue.depth warn:>2000 crit:>5000", true, false},
+		{"status warn:>1000", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			assertion, err := ParseJSONAssertion(tt.source)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, _, err := assertion.Evaluate(data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Evaluate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("Evaluate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJSONAssertion_Check(t *testing.T) {
+	var data interface{}
+
+	if err := json.Unmarshal([]byte(`{"status": "ok", "queue": {"depth": 1200}}`), &data); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		source string
+		want   *sensulib.Error
+	}{
+		{`status == "ok"`, nil},
+		{`status == "down"`, sensulib.Crit(errors.New(`status is "ok", expected == "down"`))},
+		{"warn:queue.depth < 1000", sensulib.Warn(errors.New("queue.depth is 1200, expected < 1000"))},
+		{"queue.depth warn:>2000 crit:>5000", nil},
+		{"queue.depth warn:>1000 crit:>5000", sensulib.Warn(errors.New("queue.depth is 1200, limit is >1000"))},
+		{"queue.depth warn:>1000 crit:>1100", sensulib.Crit(errors.New("queue.depth is 1200, limit is >1100"))},
+		{"queue.depth crit:>=1200", sensulib.Crit(errors.New("queue.depth is 1200, limit is >=1200"))},
+		{"status warn:>1000", sensulib.Crit(errors.New(`status is "ok", which is not a number`))},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.source, func(t *testing.T) {
+			assertion, err := ParseJSONAssertion(tt.source)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := deep.Equal(assertion.Check(data), tt.want); diff != nil {
+				t.Error(diff)
+			}
+		})
+	}
+}
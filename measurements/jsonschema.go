@@ -0,0 +1,64 @@
+package measurements
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// SchemaViolations returns leaf validation errors, prefixed with JSON
+// pointers of the failing values. Violations are sorted by instance location,
+// then by keyword location, as the validator reports them in no particular
+// order.
+func SchemaViolations(verr *jsonschema.ValidationError) []string {
+	leaves := schemaLeaves(verr)
+
+	sort.SliceStable(leaves, func(i, j int) bool {
+		if leaves[i].InstanceLocation != leaves[j].InstanceLocation {
+			return leaves[i].InstanceLocation < leaves[j].InstanceLocation
+		}
+
+		return leaves[i].KeywordLocation < leaves[j].KeywordLocation
+	})
+
+	violations := make([]string, len(leaves))
+
+	for i, leaf := range leaves {
+		violations[i] = fmt.Sprintf("#%s: %s", leaf.InstanceLocation, leaf.Message)
+	}
+
+	return violations
+}
+
+func schemaLeaves(verr *jsonschema.ValidationError) []*jsonschema.ValidationError {
+	if len(verr.Causes) == 0 {
+		return []*jsonschema.ValidationError{verr}
+	}
+
+	var leaves []*jsonschema.ValidationError
+
+	for _, cause := range verr.Causes {
+		leaves = append(leaves, schemaLeaves(cause)...)
+	}
+
+	return leaves
+}
+
+// SummarizeViolations joins at most limit violations, and notes how many more
+// there are. Zero limit shows all violations.
+func SummarizeViolations(violations []string, limit uint) string {
+	shown := violations
+
+	if limit > 0 && uint(len(shown)) > limit {
+		shown = shown[:limit]
+	}
+
+	msg := strings.Join(shown, "; ")
+	if len(shown) < len(violations) {
+		msg = fmt.Sprintf("%s (and %d more)", msg, len(violations)-len(shown))
+	}
+
+	return msg
+}
@@ -0,0 +1,95 @@
+package measurements
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/go-test/deep"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+func TestSchemaViolations(t *testing.T) {
+	schema, err := jsonschema.Compile("testdata/jsonschema/draft7.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		body string
+		want []string
+	}{
+		{"valid", `{"status": "ok", "queue": {"depth": 10}}`, nil},
+		{"missing property", `{"status": "ok"}`, []string{"#: missing properties: 'queue'"}},
+		{
+			"nested value",
+			`{"status": "ok", "queue": {"depth": 1200}}`,
+			[]string{"#/queue/depth: must be <= 1000 but found 1200"},
+		},
+		{
+			"multiple violations",
+			`{"status": "down", "queue": {"depth": "deep"}, "nodes": ["a", 2]}`,
+			[]string{
+				"#/nodes/1: expected string, but got number",
+				"#/queue/depth: expected integer, but got string",
+				`#/status: value must be one of "ok", "degraded"`,
+			},
+		},
+		{
+			"multiple violations of a value",
+			`{"status": "ok", "queue": {}, "name": "A1"}`,
+			[]string{
+				"#/name: length must be >= 3, but got 2",
+				"#/name: does not match pattern '^[a-z]+$'",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			var data interface{}
+			if err := json.Unmarshal([]byte(tt.body), &data); err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+
+			var verr *jsonschema.ValidationError
+			if err := schema.Validate(data); errors.As(err, &verr) {
+				got = SchemaViolations(verr)
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := deep.Equal(got, tt.want); diff != nil {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestSummarizeViolations(t *testing.T) {
+	violations := []string{"#/a: first", "#/b: second", "#/c: third"}
+	tests := []struct {
+		name  string
+		limit uint
+		want  string
+	}{
+		{"unlimited", 0, "#/a: first; #/b: second; #/c: third"},
+		{"below limit", 5, "#/a: first; #/b: second; #/c: third"},
+		{"at limit", 3, "#/a: first; #/b: second; #/c: third"},
+		{"cut off", 2, "#/a: first; #/b: second (and 1 more)"},
+		{"first only", 1, "#/a: first (and 2 more)"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SummarizeViolations(violations, tt.limit); got != tt.want {
+				t.Errorf("SummarizeViolations() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package measurements
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/julian7/sensulib"
+	"github.com/shirou/gopsutil/v3/mem"
+)
+
+// MemoryThresholds contains alert levels of available memory, swap usage,
+// and swap activity. Zero thresholds are not checked.
+type MemoryThresholds struct {
+	Warn      float64 // available memory, in percent
+	Crit      float64
+	WarnBytes uint64 // available memory, in bytes
+	CritBytes uint64
+	SWarn     float64 // used swap, in percent
+	SCrit     float64
+	RWarn     uint64 // swap-in or swap-out rate, in bytes per second
+	RCrit     uint64
+}
+
+// SwapRates contains swap-in and swap-out rates, in bytes per second
+type SwapRates struct {
+	In  float64
+	Out float64
+}
+
+type swapCounters struct {
+	Sin  uint64 `json:"sin"`
+	Sout uint64 `json:"sout"`
+}
+
+// SwapRatesSince returns swap rates since the counters saved in the state
+// file by the previous run, and saves the current counters. It returns nil
+// rates on the first run, and after counter resets (like reboots).
+func SwapRatesSince(state string, now time.Time, swap *mem.SwapMemoryStat) (*SwapRates, error) {
+	var prev swapCounters
+
+	// missing or unreadable state is replaced on save
+	prevTime, err := LoadState(state, &prev)
+
+	if err := SaveState(state, now, swapCounters{Sin: swap.Sin, Sout: swap.Sout}); err != nil {
+		return nil, err
+	}
+
+	elapsed := now.Sub(prevTime).Seconds()
+	if err != nil || elapsed <= 0 || swap.Sin < prev.Sin || swap.Sout < prev.Sout {
+		return nil, nil
+	}
+
+	return &SwapRates{
+		In:  float64(swap.Sin-prev.Sin) / elapsed,
+		Out: float64(swap.Sout-prev.Sout) / elapsed,
+	}, nil
+}
+
+// AvailablePercent returns available memory in percent of total memory
+func AvailablePercent(vmem *mem.VirtualMemoryStat) float64 {
+	if vmem.Total == 0 {
+		return 0
+	}
+
+	return float64(vmem.Available) / float64(vmem.Total) * 100
+}
+
+// CheckMemory returns an error if available memory is at or below a threshold
+func (th *MemoryThresholds) CheckMemory(vmem *mem.VirtualMemoryStat) *sensulib.Error {
+	available := AvailablePercent(vmem)
+	err := fmt.Errorf(
+		"%s memory available (%s of %s)",
+		sensulib.PercentToHuman(available, 1),
+		sensulib.SizeToHuman(vmem.Available),
+		sensulib.SizeToHuman(vmem.Total),
+	)
+
+	switch {
+	case th.Crit > 0 && available <= th.Crit, th.CritBytes > 0 && vmem.Available <= th.CritBytes:
+		return sensulib.Crit(err)
+	case th.Warn > 0 && available <= th.Warn, th.WarnBytes > 0 && vmem.Available <= th.WarnBytes:
+		return sensulib.Warn(err)
+	}
+
+	return nil
+}
+
+// CheckSwap returns an error if swap usage is at or above a threshold
+func (th *MemoryThresholds) CheckSwap(swap *mem.SwapMemoryStat) *sensulib.Error {
+	if swap.Total == 0 {
+		return nil
+	}
+
+	err := fmt.Errorf(
+		"%s swap used (%s of %s)",
+		sensulib.PercentToHuman(swap.UsedPercent, 1),
+		sensulib.SizeToHuman(swap.Used),
+		sensulib.SizeToHuman(swap.Total),
+	)
+
+	switch {
+	case th.SCrit > 0 && swap.UsedPercent >= th.SCrit:
+		return sensulib.Crit(err)
+	case th.SWarn > 0 && swap.UsedPercent >= th.SWarn:
+		return sensulib.Warn(err)
+	}
+
+	return nil
+}
+
+// CheckSwapRates returns an error if swap-in or swap-out rate is at or above
+// a threshold. Missing rates are not checked.
+func (th *MemoryThresholds) CheckSwapRates(rates *SwapRates) *sensulib.Error {
+	if rates == nil {
+		return nil
+	}
+
+	highest := rates.In
+	if rates.Out > highest {
+		highest = rates.Out
+	}
+
+	err := fmt.Errorf(
+		"swapping %s/s in, %s/s out",
+		sensulib.SizeToHuman(uint64(rates.In)),
+		sensulib.SizeToHuman(uint64(rates.Out)),
+	)
+
+	switch {
+	case th.RCrit > 0 && highest >= float64(th.RCrit):
+		return sensulib.Crit(err)
+	case th.RWarn > 0 && highest >= float64(th.RWarn):
+		return sensulib.Warn(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,157 @@
+package measurements
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-test/deep"
+	"github.com/julian7/sensulib"
+	"github.com/shirou/gopsutil/v3/mem"
+)
+
+func TestMemoryThresholds_CheckMemory(t *testing.T) {
+	vmem := &mem.VirtualMemoryStat{Total: 1000, Available: 80}
+	msg := fmt.Errorf(
+		"%s memory available (%s of %s)",
+		sensulib.PercentToHuman(8, 1),
+		sensulib.SizeToHuman(80),
+		sensulib.SizeToHuman(1000),
+	)
+	tests := []struct {
+		name string
+		th   MemoryThresholds
+		want *sensulib.Error
+	}{
+		{"above thresholds", MemoryThresholds{Warn: 5, Crit: 2}, nil},
+		{"warning", MemoryThresholds{Warn: 10, Crit: 5}, sensulib.Warn(msg)},
+		{"critical", MemoryThresholds{Warn: 20, Crit: 10}, sensulib.Crit(msg)},
+		{"zero thresholds", MemoryThresholds{}, nil},
+		{"zero critical", MemoryThresholds{Warn: 5}, nil},
+		{"warning bytes", MemoryThresholds{WarnBytes: 100}, sensulib.Warn(msg)},
+		{"critical bytes", MemoryThresholds{WarnBytes: 100, CritBytes: 80}, sensulib.Crit(msg)},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			if diff := deep.Equal(tt.th.CheckMemory(vmem), tt.want); diff != nil {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestMemoryThresholds_CheckSwap(t *testing.T) {
+	msg := fmt.Errorf(
+		"%s swap used (%s of %s)",
+		sensulib.PercentToHuman(50, 1),
+		sensulib.SizeToHuman(500),
+		sensulib.SizeToHuman(1000),
+	)
+	tests := []struct {
+		name string
+		swap mem.SwapMemoryStat
+		th   MemoryThresholds
+		want *sensulib.Error
+	}{
+		{"no swap", mem.SwapMemoryStat{}, MemoryThresholds{SWarn: 1, SCrit: 2}, nil},
+		{"below thresholds", mem.SwapMemoryStat{Total: 1000, Used: 500, UsedPercent: 50}, MemoryThresholds{SWarn: 60}, nil},
+		{"warning", mem.SwapMemoryStat{Total: 1000, Used: 500, UsedPercent: 50}, MemoryThresholds{SWarn: 50, SCrit: 80}, sensulib.Warn(msg)},
+		{"critical", mem.SwapMemoryStat{Total: 1000, Used: 500, UsedPercent: 50}, MemoryThresholds{SWarn: 30, SCrit: 50}, sensulib.Crit(msg)},
+		{"zero thresholds", mem.SwapMemoryStat{Total: 1000, Used: 500, UsedPercent: 50}, MemoryThresholds{}, nil},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			if diff := deep.Equal(tt.th.CheckSwap(&tt.swap), tt.want); diff != nil {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestMemoryThresholds_CheckSwapRates(t *testing.T) {
+	msg := fmt.Errorf("swapping %s/s in, %s/s out", sensulib.SizeToHuman(100), sensulib.SizeToHuman(2000))
+	tests := []struct {
+		name  string
+		rates *SwapRates
+		th    MemoryThresholds
+		want  *sensulib.Error
+	}{
+		{"not measured", nil, MemoryThresholds{RWarn: 1}, nil},
+		{"below thresholds", &SwapRates{In: 100, Out: 2000}, MemoryThresholds{RWarn: 4096}, nil},
+		{"warning on swap-out", &SwapRates{In: 100, Out: 2000}, MemoryThresholds{RWarn: 1024, RCrit: 4096}, sensulib.Warn(msg)},
+		{"critical", &SwapRates{In: 100, Out: 2000}, MemoryThresholds{RWarn: 1024, RCrit: 2000}, sensulib.Crit(msg)},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			if diff := deep.Equal(tt.th.CheckSwapRates(tt.rates), tt.want); diff != nil {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestSwapRatesSince(t *testing.T) {
+	state := filepath.Join(t.TempDir(), "memory.state")
+	start := time.Date(2022, 3, 1, 12, 0, 0, 0, time.UTC)
+	steps := []struct {
+		name  string
+		now   time.Time
+		swap  mem.SwapMemoryStat
+		setup func()
+		want  *SwapRates
+	}{
+		{"first run", start, mem.SwapMemoryStat{Sin: 1000, Sout: 2000}, nil, nil},
+		{
+			"second run",
+			start.Add(10 * time.Second),
+			mem.SwapMemoryStat{Sin: 2000, Sout: 7000},
+			nil,
+			&SwapRates{In: 100, Out: 500},
+		},
+		{"counter reset", start.Add(20 * time.Second), mem.SwapMemoryStat{Sin: 10, Sout: 20}, nil, nil},
+		{
+			"after reset",
+			start.Add(30 * time.Second),
+			mem.SwapMemoryStat{Sin: 10, Sout: 1020},
+			nil,
+			&SwapRates{In: 0, Out: 100},
+		},
+		{"clock went backwards", start, mem.SwapMemoryStat{Sin: 20, Sout: 2000}, nil, nil},
+		{
+			"corrupt state",
+			start.Add(40 * time.Second),
+			mem.SwapMemoryStat{Sin: 20, Sout: 2000},
+			func() {
+				if err := os.WriteFile(state, []byte("{"), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			},
+			nil,
+		},
+		{"after corrupt state", start.Add(50 * time.Second), mem.SwapMemoryStat{Sin: 20, Sout: 3000}, nil, &SwapRates{Out: 100}},
+	}
+
+	// steps build on each other's state
+	for _, step := range steps {
+		if step.setup != nil {
+			step.setup()
+		}
+
+		got, err := SwapRatesSince(state, step.now, &step.swap)
+		if err != nil {
+			t.Fatalf("%s: SwapRatesSince() error = %v", step.name, err)
+		}
+
+		if diff := deep.Equal(got, step.want); diff != nil {
+			t.Errorf("%s: %v", step.name, diff)
+		}
+	}
+}
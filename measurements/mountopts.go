@@ -0,0 +1,171 @@
+package measurements
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/julian7/sensulib"
+	"github.com/shirou/gopsutil/v3/disk"
+	"github.com/spf13/pflag"
+)
+
+// MountOptions checks mount options of partitions against per-mountpoint rules
+type MountOptions struct {
+	rulesS   []string
+	rules    map[string]*mountRule
+	seen     map[string]bool
+	readonly bool
+}
+
+type mountRule struct {
+	required  []string
+	forbidden []string
+}
+
+func (conf *MountOptions) SetFlags(flags *pflag.FlagSet) {
+	flags.StringArrayVar(&conf.rulesS, "mntopt", nil, "Mount option rule in MOUNTPOINT:OPT[,OPT...] form;"+
+		" prefix OPT with ! to forbid it (repeatable)")
+	flags.BoolVar(&conf.readonly, "readonly", false, "Alert on read-only filesystems, unless --mntopt requires ro")
+}
+
+func (conf *MountOptions) Check() error {
+	conf.rules = map[string]*mountRule{}
+	conf.seen = map[string]bool{}
+
+	for _, item := range conf.rulesS {
+		mountpoint, opts, err := parseMountRule(item)
+		if err != nil {
+			return fmt.Errorf("cannot interpret --mntopt %q: %w", item, err)
+		}
+
+		rule, ok := conf.rules[mountpoint]
+		if !ok {
+			rule = &mountRule{}
+			conf.rules[mountpoint] = rule
+		}
+
+		for _, opt := range opts {
+			if strings.HasPrefix(opt, "!") {
+				rule.forbidden = append(rule.forbidden, opt[1:])
+			} else {
+				rule.required = append(rule.required, opt)
+			}
+		}
+	}
+
+	return nil
+}
+
+func parseMountRule(rule string) (string, []string, error) {
+	idx := strings.LastIndex(rule, ":")
+	if idx < 1 {
+		return "", nil, fmt.Errorf("no mount point provided")
+	}
+
+	opts := strings.Split(rule[idx+1:], ",")
+	for _, opt := range opts {
+		if len(strings.TrimPrefix(opt, "!")) == 0 {
+			return "", nil, fmt.Errorf("empty option")
+		}
+	}
+
+	return rule[:idx], opts, nil
+}
+
+// HasRules returns true if there are any mount option rules to check
+func (conf *MountOptions) HasRules() bool {
+	return len(conf.rules) > 0
+}
+
+// CheckRules verifies a partition's mount options against its rule, if any
+func (conf *MountOptions) CheckRules(part *disk.PartitionStat) *sensulib.Error {
+	rule, ok := conf.rules[part.Mountpoint]
+	if !ok {
+		return nil
+	}
+
+	conf.seen[part.Mountpoint] = true
+
+	var problems []string
+
+	missing := missingOpts(rule.required, part.Opts)
+	if len(missing) > 0 {
+		problems = append(problems, "missing "+strings.Join(missing, ","))
+	}
+
+	forbidden := presentOpts(rule.forbidden, part.Opts)
+	if len(forbidden) > 0 {
+		problems = append(problems, "forbidden "+strings.Join(forbidden, ","))
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+
+	return sensulib.Warn(fmt.Errorf(
+		"%s mount options %s",
+		part.Mountpoint,
+		strings.Join(problems, "; "),
+	))
+}
+
+// CheckMissing reports mount points having rules, but not seen by CheckRules
+func (conf *MountOptions) CheckMissing() *sensulib.Error {
+	var missing []string
+
+	for mountpoint := range conf.rules {
+		if !conf.seen[mountpoint] {
+			missing = append(missing, mountpoint)
+		}
+	}
+
+	if len(missing) == 0 {
+		return nil
+	}
+
+	sort.Strings(missing)
+
+	return sensulib.Warn(fmt.Errorf("not mounted: %s", strings.Join(missing, ", ")))
+}
+
+// CheckReadonly alerts on read-only partitions, if --readonly is set
+func (conf *MountOptions) CheckReadonly(part *disk.PartitionStat) *sensulib.Error {
+	if !conf.readonly {
+		return nil
+	}
+
+	if rule, ok := conf.rules[part.Mountpoint]; ok && hasOpt([]string{"ro"}, rule.required) {
+		return nil
+	}
+
+	if !hasOpt([]string{"ro"}, part.Opts) && !readOnly(part.Mountpoint) {
+		return nil
+	}
+
+	return sensulib.Crit(fmt.Errorf("%s is mounted read-only", part.Mountpoint))
+}
+
+func missingOpts(needles []string, haystack []string) []string {
+	var ret []string
+
+	for _, needle := range needles {
+		if !hasOpt([]string{needle}, haystack) {
+			ret = append(ret, needle)
+		}
+	}
+
+	return ret
+}
+
+func presentOpts(needles []string, haystack []string) []string {
+	var ret []string
+
+	for _, needle := range needles {
+		if hasOpt([]string{needle}, haystack) {
+			ret = append(ret, needle)
+		}
+	}
+
+	return ret
+}
@@ -0,0 +1,76 @@
+package measurements
+
+import (
+	"testing"
+
+	"github.com/shirou/gopsutil/v3/disk"
+)
+
+func TestMountOptions_CheckRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   []string
+		part    disk.PartitionStat
+		wantErr bool
+	}{
+		{
+			"no rules",
+			nil,
+			disk.PartitionStat{Mountpoint: "/tmp", Opts: []string{"rw"}},
+			false,
+		},
+		{
+			"required options present",
+			[]string{"/tmp:nodev,nosuid,noexec"},
+			disk.PartitionStat{Mountpoint: "/tmp", Opts: []string{"rw", "nosuid", "nodev", "noexec"}},
+			false,
+		},
+		{
+			"required option missing",
+			[]string{"/tmp:nodev,nosuid,noexec"},
+			disk.PartitionStat{Mountpoint: "/tmp", Opts: []string{"rw", "nosuid", "nodev"}},
+			true,
+		},
+		{
+			"forbidden option present",
+			[]string{"/data:!ro"},
+			disk.PartitionStat{Mountpoint: "/data", Opts: []string{"ro", "relatime"}},
+			true,
+		},
+		{
+			"rules merge",
+			[]string{"/data:!ro", "/data:noatime"},
+			disk.PartitionStat{Mountpoint: "/data", Opts: []string{"rw", "relatime"}},
+			true,
+		},
+		{
+			"other mount point",
+			[]string{"/tmp:noexec"},
+			disk.PartitionStat{Mountpoint: "/var/tmp", Opts: []string{"rw"}},
+			false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := &MountOptions{rulesS: tt.rules}
+			if err := conf.Check(); err != nil {
+				t.Fatal(err)
+			}
+
+			err := conf.CheckRules(&tt.part)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CheckRules() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMountOptions_Check(t *testing.T) {
+	for _, rule := range []string{"noexec", ":noexec", "/tmp:", "/tmp:nodev,", "/tmp:!"} {
+		conf := &MountOptions{rulesS: []string{rule}}
+		if err := conf.Check(); err == nil {
+			t.Errorf("Check() accepted invalid rule %q", rule)
+		}
+	}
+}
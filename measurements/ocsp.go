@@ -0,0 +1,234 @@
+package measurements
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/julian7/sensulib"
+	"github.com/karrick/tparse"
+	"github.com/spf13/pflag"
+	"golang.org/x/crypto/ocsp"
+)
+
+// OCSPResponseLimit is the maximum size of OCSP responses read from
+// responders
+const OCSPResponseLimit = 64 * 1024
+
+// oidTLSFeature is the TLS feature extension (RFC 7633), which marks
+// certificates as must-staple
+var oidTLSFeature = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 1, 24}
+
+// tlsFeatureStatusRequest is the status_request TLS extension ID
+const tlsFeatureStatusRequest = 5
+
+var ocspRevocationReasons = map[int]string{
+	ocsp.Unspecified:          "unspecified",
+	ocsp.KeyCompromise:        "key compromise",
+	ocsp.CACompromise:         "CA compromise",
+	ocsp.AffiliationChanged:   "affiliation changed",
+	ocsp.Superseded:           "superseded",
+	ocsp.CessationOfOperation: "cessation of operation",
+	ocsp.CertificateHold:      "certificate hold",
+	ocsp.RemoveFromCRL:        "remove from CRL",
+	ocsp.PrivilegeWithdrawn:   "privilege withdrawn",
+	ocsp.AACompromise:         "AA compromise",
+}
+
+// OCSPChecker checks revocation status of TLS server certificates
+type OCSPChecker struct {
+	enabled bool
+	maxAgeS string
+	maxAge  time.Duration
+	// Client is used for querying OCSP responders
+	Client *http.Client
+	// Roots are used for finding the issuer of unverified connections'
+	// certificates (system CAs, if nil)
+	Roots *x509.CertPool
+}
+
+func (conf *OCSPChecker) SetFlags(flags *pflag.FlagSet) {
+	flags.BoolVar(&conf.enabled, "ocsp", false, "Check OCSP revocation status of server certificate")
+	flags.StringVar(&conf.maxAgeS, "ocsp-max-age", "", "Warn if OCSP response is older than this duration (like 7d)")
+}
+
+func (conf *OCSPChecker) Check() error {
+	if len(conf.maxAgeS) == 0 {
+		return nil
+	}
+
+	until, err := tparse.ParseNow(time.RFC3339, "now+"+conf.maxAgeS)
+	if err != nil {
+		return fmt.Errorf("cannot parse --ocsp-max-age: %w", err)
+	}
+
+	conf.maxAge = time.Until(until)
+
+	return nil
+}
+
+// Enabled returns true if OCSP checking is requested
+func (conf *OCSPChecker) Enabled() bool {
+	return conf.enabled
+}
+
+// Verify checks OCSP status of the server certificate, using the stapled
+// response if present, or querying the certificate's OCSP responder
+// otherwise. It returns nil if the certificate is good.
+func (conf *OCSPChecker) Verify(cs tls.ConnectionState) *sensulib.Error {
+	leaf, issuer := conf.certificates(cs)
+	if leaf == nil || issuer == nil {
+		return sensulib.Unknown(errors.New("OCSP check requires server certificate and its issuer"))
+	}
+
+	raw := cs.OCSPResponse
+	source := "stapled"
+
+	if len(raw) == 0 {
+		if MustStaple(leaf) {
+			return sensulib.Crit(errors.New("must-staple certificate without stapled OCSP response"))
+		}
+
+		if len(leaf.OCSPServer) == 0 {
+			return sensulib.Warn(errors.New("no stapled OCSP response, and certificate has no OCSP responder"))
+		}
+
+		var err error
+
+		source = leaf.OCSPServer[0]
+
+		raw, err = conf.query(source, leaf, issuer)
+		if err != nil {
+			return sensulib.Crit(fmt.Errorf("OCSP query to %s: %w", source, err))
+		}
+	}
+
+	resp, err := ocsp.ParseResponseForCert(raw, leaf, issuer)
+	if err != nil {
+		return sensulib.Crit(fmt.Errorf("invalid OCSP response (%s): %w", source, err))
+	}
+
+	switch resp.Status {
+	case ocsp.Revoked:
+		return sensulib.Crit(fmt.Errorf(
+			"certificate %s revoked at %s (%s)",
+			leaf.Subject,
+			resp.RevokedAt.Format(time.RFC3339),
+			ocspRevocationReason(resp.RevocationReason),
+		))
+	case ocsp.Unknown:
+		return sensulib.Warn(fmt.Errorf("OCSP status of certificate %s is unknown (%s)", leaf.Subject, source))
+	}
+
+	if !resp.NextUpdate.IsZero() && time.Now().After(resp.NextUpdate) {
+		return sensulib.Warn(fmt.Errorf(
+			"stale OCSP response (%s): next update was due at %s",
+			source,
+			resp.NextUpdate.Format(time.RFC3339),
+		))
+	}
+
+	if conf.maxAge > 0 && time.Since(resp.ThisUpdate) > conf.maxAge {
+		return sensulib.Warn(fmt.Errorf(
+			"stale OCSP response (%s): produced at %s",
+			source,
+			resp.ThisUpdate.Format(time.RFC3339),
+		))
+	}
+
+	return nil
+}
+
+func (conf *OCSPChecker) query(server string, leaf, issuer *x509.Certificate) ([]byte, error) {
+	req, err := ocsp.CreateRequest(leaf, issuer, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	client := conf.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Post(server, "application/ocsp-request", bytes.NewReader(req))
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("responded with %s", resp.Status)
+	}
+
+	return ioutil.ReadAll(io.LimitReader(resp.Body, OCSPResponseLimit))
+}
+
+// certificates returns the server certificate and its issuer, preferring
+// the verified chain. Unverified chains are verified against Roots, falling
+// back to the certificates sent by the server.
+func (conf *OCSPChecker) certificates(cs tls.ConnectionState) (*x509.Certificate, *x509.Certificate) {
+	chain := cs.PeerCertificates
+
+	if len(cs.VerifiedChains) == 0 && len(chain) > 0 {
+		intermediates := x509.NewCertPool()
+		for _, cert := range chain[1:] {
+			intermediates.AddCert(cert)
+		}
+
+		chains, err := chain[0].Verify(x509.VerifyOptions{Roots: conf.Roots, Intermediates: intermediates})
+		if err == nil {
+			cs.VerifiedChains = chains
+		}
+	}
+
+	if len(cs.VerifiedChains) > 0 {
+		chain = cs.VerifiedChains[0]
+	}
+
+	switch len(chain) {
+	case 0:
+		return nil, nil
+	case 1:
+		return chain[0], nil
+	}
+
+	return chain[0], chain[1]
+}
+
+// MustStaple returns true if the certificate requires stapled OCSP responses
+func MustStaple(cert *x509.Certificate) bool {
+	for _, ext := range cert.Extensions {
+		if !ext.Id.Equal(oidTLSFeature) {
+			continue
+		}
+
+		var features []int
+		if _, err := asn1.Unmarshal(ext.Value, &features); err != nil {
+			return false
+		}
+
+		for _, feature := range features {
+			if feature == tlsFeatureStatusRequest {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func ocspRevocationReason(reason int) string {
+	if name, ok := ocspRevocationReasons[reason]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("reason %d", reason)
+}
@@ -0,0 +1,184 @@
+package measurements
+
+import (
+	"crypto"
+	"crypto/elliptic"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+// ocspResponder is a local OCSP responder stand-in, answering with a fixed
+// status
+type ocspResponder struct {
+	t          *testing.T
+	issuer     *x509.Certificate
+	key        crypto.Signer
+	status     int
+	nextUpdate time.Time
+}
+
+func (responder *ocspResponder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		responder.t.Error(err)
+		return
+	}
+
+	req, err := ocsp.ParseRequest(body)
+	if err != nil {
+		responder.t.Error(err)
+		return
+	}
+
+	resp, err := responder.create(req.SerialNumber.Int64())
+	if err != nil {
+		responder.t.Error(err)
+		return
+	}
+
+	_, _ = w.Write(resp)
+}
+
+func (responder *ocspResponder) create(serial int64) ([]byte, error) {
+	template := ocsp.Response{
+		Status:       responder.status,
+		ThisUpdate:   time.Now().Add(-time.Hour),
+		NextUpdate:   responder.nextUpdate,
+		RevokedAt:    time.Now().Add(-time.Minute),
+		SerialNumber: big.NewInt(serial),
+	}
+
+	return ocsp.CreateResponse(responder.issuer, responder.issuer, template, responder.key)
+}
+
+func TestOCSPChecker_Verify(t *testing.T) {
+	ca, caKey := issueCert(t, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "ca"},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+	}, nil, nil, elliptic.P256())
+
+	responder := &ocspResponder{t: t, issuer: ca, key: caKey}
+	server := httptest.NewServer(responder)
+
+	defer server.Close()
+
+	mustStaple, err := asn1.Marshal([]int{tlsFeatureStatusRequest})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	leaf, _ := issueCert(t, &x509.Certificate{
+		Subject:    pkix.Name{CommonName: "localhost"},
+		OCSPServer: []string{server.URL},
+	}, ca, caKey, elliptic.P256())
+	stapledLeaf, _ := issueCert(t, &x509.Certificate{
+		Subject:         pkix.Name{CommonName: "localhost"},
+		ExtraExtensions: []pkix.Extension{{Id: oidTLSFeature, Value: mustStaple}},
+	}, ca, caKey, elliptic.P256())
+	noResponderLeaf, _ := issueCert(t, &x509.Certificate{
+		Subject: pkix.Name{CommonName: "localhost"},
+	}, ca, caKey, elliptic.P256())
+
+	staple := func(status int, nextUpdate time.Time) []byte {
+		responder.status = status
+		responder.nextUpdate = nextUpdate
+
+		resp, err := responder.create(stapledLeaf.SerialNumber.Int64())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return resp
+	}
+
+	tests := []struct {
+		name       string
+		leaf       *x509.Certificate
+		staple     []byte
+		status     int
+		nextUpdate time.Time
+		maxAge     time.Duration
+		want       string
+	}{
+		{"good from responder", leaf, nil, ocsp.Good, time.Now().Add(time.Hour), 0, ""},
+		{"revoked from responder", leaf, nil, ocsp.Revoked, time.Now().Add(time.Hour), 0, "revoked"},
+		{"unknown from responder", leaf, nil, ocsp.Unknown, time.Now().Add(time.Hour), 0, "unknown"},
+		{"stale from responder", leaf, nil, ocsp.Good, time.Now().Add(-time.Minute), 0, "next update was due"},
+		{"older than max age", leaf, nil, ocsp.Good, time.Now().Add(time.Hour), time.Minute, "produced at"},
+		{"good stapled", stapledLeaf, staple(ocsp.Good, time.Now().Add(time.Hour)), 0, time.Time{}, 0, ""},
+		{"revoked stapled", stapledLeaf, staple(ocsp.Revoked, time.Now().Add(time.Hour)), 0, time.Time{}, 0, "revoked"},
+		{"must-staple without staple", stapledLeaf, nil, ocsp.Good, time.Time{}, 0, "must-staple"},
+		{"no responder", noResponderLeaf, nil, ocsp.Good, time.Time{}, 0, "no OCSP responder"},
+		{"invalid staple", stapledLeaf, []byte("invalid"), 0, time.Time{}, 0, "invalid OCSP response"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			responder.t = t
+			responder.status = tt.status
+			responder.nextUpdate = tt.nextUpdate
+
+			conf := &OCSPChecker{enabled: true, maxAge: tt.maxAge}
+
+			err := conf.Verify(tls.ConnectionState{
+				PeerCertificates: []*x509.Certificate{tt.leaf, ca},
+				OCSPResponse:     tt.staple,
+			})
+
+			switch {
+			case len(tt.want) == 0 && err != nil:
+				t.Errorf("OCSPChecker.Verify() error = %v, wanted none", err)
+			case len(tt.want) > 0 && err == nil:
+				t.Errorf("OCSPChecker.Verify() error = nil, wanted %q", tt.want)
+			case len(tt.want) > 0 && !strings.Contains(err.Error(), tt.want):
+				t.Errorf("OCSPChecker.Verify() error = %v, wanted %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestMustStaple(t *testing.T) {
+	value, err := asn1.Marshal([]int{tlsFeatureStatusRequest})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	other, err := asn1.Marshal([]int{17})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		extensions []pkix.Extension
+		want       bool
+	}{
+		{"no extension", nil, false},
+		{"status request", []pkix.Extension{{Id: oidTLSFeature, Value: value}}, true},
+		{"other feature", []pkix.Extension{{Id: oidTLSFeature, Value: other}}, false},
+		{"invalid value", []pkix.Extension{{Id: oidTLSFeature, Value: []byte("invalid")}}, false},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MustStaple(&x509.Certificate{Extensions: tt.extensions}); got != tt.want {
+				t.Errorf("MustStaple() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package measurements
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+// ErrProbeTimeout is returned when a write probe doesn't finish in time
+var ErrProbeTimeout = errors.New("probe timed out")
+
+// WriteProbe checks whether a filesystem is writable, by creating, syncing,
+// reading back, and removing a small file
+type WriteProbe struct {
+	enabled  bool
+	dir      string
+	timeoutS string
+	timeout  time.Duration
+	// write writes and syncs probe content; tests can replace it
+	write func(file *os.File, content []byte) error
+}
+
+func (conf *WriteProbe) SetFlags(flags *pflag.FlagSet) {
+	flags.BoolVar(&conf.enabled, "probe", false, "Probe writability of selected filesystems")
+	flags.StringVar(&conf.dir, "probe-dir", "", "Probe directory, relative to mount point; missing directories are skipped")
+	flags.StringVar(&conf.timeoutS, "probe-timeout", "5s", "Timeout for a single write probe")
+}
+
+func (conf *WriteProbe) Check() error {
+	var err error
+
+	if !conf.enabled {
+		return nil
+	}
+
+	conf.timeout, err = time.ParseDuration(conf.timeoutS)
+	if err != nil {
+		return fmt.Errorf("cannot parse --probe-timeout: %w", err)
+	}
+
+	if conf.timeout <= 0 {
+		return errors.New("--probe-timeout should be set")
+	}
+
+	return nil
+}
+
+// Enabled returns true if write probes are requested
+func (conf *WriteProbe) Enabled() bool {
+	return conf.enabled
+}
+
+// Probe runs a write probe on a mount point. It returns an error wrapping
+// os.ErrNotExist if the probe directory doesn't exist.
+func (conf *WriteProbe) Probe(mountpoint string) (time.Duration, error) {
+	dir := filepath.Join(mountpoint, conf.dir)
+
+	if _, err := os.Stat(dir); err != nil {
+		return 0, err
+	}
+
+	done := make(chan error, 1)
+	abandoned := make(chan struct{})
+	start := time.Now()
+
+	go func() {
+		done <- conf.probe(dir, abandoned)
+	}()
+
+	select {
+	case err := <-done:
+		return time.Since(start), err
+	case <-time.After(conf.timeout):
+		close(abandoned)
+		return conf.timeout, ErrProbeTimeout
+	}
+}
+
+// probe runs the write probe in dir. The probe file is always removed, even
+// if the caller has abandoned the probe in the meantime; in that case, the
+// remaining steps are skipped.
+func (conf *WriteProbe) probe(dir string, abandoned <-chan struct{}) error {
+	content := []byte(fmt.Sprintf("sensu-base-checks write probe %d\n", time.Now().UnixNano()))
+
+	file, err := os.CreateTemp(dir, ".sensu-base-checks-probe-*")
+	if err != nil {
+		return fmt.Errorf("creating file: %w", err)
+	}
+
+	name := file.Name()
+	defer os.Remove(name)
+
+	write := conf.write
+	if write == nil {
+		write = writeAndSync
+	}
+
+	if err := write(file, content); err != nil {
+		file.Close()
+		return err
+	}
+
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("closing %s: %w", name, err)
+	}
+
+	select {
+	case <-abandoned:
+		return ErrProbeTimeout
+	default:
+	}
+
+	readback, err := os.ReadFile(name)
+	if err != nil {
+		return fmt.Errorf("reading back %s: %w", name, err)
+	}
+
+	if !bytes.Equal(content, readback) {
+		return fmt.Errorf("reading back %s: content mismatch", name)
+	}
+
+	if err := os.Remove(name); err != nil {
+		return fmt.Errorf("removing %s: %w", name, err)
+	}
+
+	return nil
+}
+
+func writeAndSync(file *os.File, content []byte) error {
+	if _, err := file.Write(content); err != nil {
+		return fmt.Errorf("writing %s: %w", file.Name(), err)
+	}
+
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf("syncing %s: %w", file.Name(), err)
+	}
+
+	return nil
+}
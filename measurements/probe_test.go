@@ -0,0 +1,79 @@
+package measurements
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestWriteProbe_Probe(t *testing.T) {
+	dir := t.TempDir()
+	conf := &WriteProbe{enabled: true, timeoutS: "5s"}
+
+	if err := conf.Check(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := conf.Probe(dir); err != nil {
+		t.Errorf("Probe() error = %v", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 0 {
+		t.Errorf("Probe() left %d files behind", len(entries))
+	}
+
+	conf.dir = "missing"
+	if _, err := conf.Probe(dir); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Probe() on missing directory error = %v, want os.ErrNotExist", err)
+	}
+}
+
+func TestWriteProbe_ProbeTimeout(t *testing.T) {
+	dir := t.TempDir()
+	release := make(chan struct{})
+	conf := &WriteProbe{
+		enabled:  true,
+		timeoutS: "50ms",
+		write: func(file *os.File, content []byte) error {
+			<-release
+
+			return writeAndSync(file, content)
+		},
+	}
+
+	if err := conf.Check(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := conf.Probe(dir); !errors.Is(err, ErrProbeTimeout) {
+		t.Fatalf("Probe() error = %v, want ErrProbeTimeout", err)
+	}
+
+	// the hung write finishes after the caller gave up
+	close(release)
+
+	deadline := time.Now().Add(5 * time.Second)
+
+	for {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(entries) == 0 {
+			return
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("abandoned probe left %d files behind", len(entries))
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,241 @@
+package measurements
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/shirou/gopsutil/v3/process"
+	"github.com/spf13/pflag"
+)
+
+// ProcessFilter selects processes by name, command line, user, or pidfile
+type ProcessFilter struct {
+	name    string
+	matchS  string
+	match   *regexp.Regexp
+	user    string
+	pidfile string
+	pid     int32
+}
+
+// ProcessInfo contains identifying information of a process
+type ProcessInfo struct {
+	PID     int32
+	Name    string
+	Cmdline string
+	User    string
+	Zombie  bool
+	Process *process.Process
+}
+
+func (conf *ProcessFilter) SetFlags(flags *pflag.FlagSet) {
+	flags.StringVarP(&conf.name, "name", "n", "", "Match processes with this exact process name")
+	flags.StringVarP(&conf.matchS, "match", "m", "", "Match processes with command line matching this regular expression")
+	flags.StringVarP(&conf.user, "user", "u", "", "Match processes running as this user")
+	flags.StringVarP(&conf.pidfile, "pidfile", "p", "", "Match process with PID read from this file")
+}
+
+func (conf *ProcessFilter) Check() error {
+	if len(conf.name) == 0 && len(conf.matchS) == 0 && len(conf.user) == 0 && len(conf.pidfile) == 0 {
+		return errors.New("at least one of --name, --match, --user, or --pidfile should be set")
+	}
+
+	if len(conf.matchS) > 0 {
+		var err error
+
+		conf.match, err = regexp.Compile(conf.matchS)
+		if err != nil {
+			return fmt.Errorf("cannot compile --match: %w", err)
+		}
+	}
+
+	if len(conf.pidfile) > 0 {
+		pid, err := ReadPidfile(conf.pidfile)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+
+		conf.pid = pid
+	}
+
+	return nil
+}
+
+// ReadPidfile returns PID stored in a pidfile
+func ReadPidfile(pidfile string) (int32, error) {
+	contents, err := os.ReadFile(pidfile)
+	if err != nil {
+		return 0, fmt.Errorf("cannot read pidfile: %w", err)
+	}
+
+	pid, err := strconv.ParseInt(strings.TrimSpace(string(contents)), 10, 32)
+	if err != nil || pid <= 0 {
+		return 0, fmt.Errorf("pidfile %s has no valid PID", pidfile)
+	}
+
+	return int32(pid), nil
+}
+
+// String returns a human readable description of the filter
+func (conf *ProcessFilter) String() string {
+	var items []string
+
+	for _, item := range []struct {
+		name  string
+		value string
+	}{
+		{"name", conf.name},
+		{"cmdline", conf.matchS},
+		{"user", conf.user},
+		{"pidfile", conf.pidfile},
+	} {
+		if len(item.value) > 0 {
+			items = append(items, fmt.Sprintf("%s %q", item.name, item.value))
+		}
+	}
+
+	return strings.Join(items, ", ")
+}
+
+// Selected returns true if process matches all set criteria
+func (conf *ProcessFilter) Selected(info *ProcessInfo) bool {
+	if len(conf.pidfile) > 0 && info.PID != conf.pid {
+		return false
+	}
+
+	if len(conf.name) > 0 && info.Name != conf.name {
+		return false
+	}
+
+	if conf.match != nil && !conf.match.MatchString(info.Cmdline) {
+		return false
+	}
+
+	if len(conf.user) > 0 && info.User != conf.user {
+		return false
+	}
+
+	return true
+}
+
+// Processes returns all running processes matching the filter, except the
+// current one, and its parent if it is a wrapper of the check (like
+// `sh -c "sensu-base-checks process ..."`), as its command line matches too
+func (conf *ProcessFilter) Processes() ([]*ProcessInfo, error) {
+	if len(conf.pidfile) > 0 && conf.pid == 0 {
+		return nil, nil
+	}
+
+	procs, err := process.Processes()
+	if err != nil {
+		return nil, fmt.Errorf("cannot list processes: %w", err)
+	}
+
+	self := int32(os.Getpid())
+	wrapper := wrapperPid(os.Getppid(), os.Args)
+	ret := []*ProcessInfo{}
+
+	for _, proc := range procs {
+		if proc.Pid == self || proc.Pid == wrapper {
+			continue
+		}
+
+		info, err := conf.processInfo(proc)
+		if err != nil {
+			// process might have exited in the meantime
+			continue
+		}
+
+		if conf.Selected(info) {
+			info.Zombie = isZombie(proc)
+			ret = append(ret, info)
+		}
+	}
+
+	return ret, nil
+}
+
+// wrapperPid returns the parent PID if the parent's command line contains
+// the check's arguments, or 0 otherwise
+func wrapperPid(ppid int, args []string) int32 {
+	parent, err := process.NewProcess(int32(ppid))
+	if err != nil {
+		return 0
+	}
+
+	cmdline, err := parent.Cmdline()
+	if err != nil || !wraps(cmdline, args) {
+		return 0
+	}
+
+	return parent.Pid
+}
+
+// wraps returns true if cmdline contains args in order. The command is
+// matched by its base name, as shells might resolve it to a full path.
+func wraps(cmdline string, args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+
+	rest := cmdline
+
+	for i, arg := range args {
+		if i == 0 {
+			arg = filepath.Base(arg)
+		}
+
+		pos := strings.Index(rest, arg)
+		if pos < 0 {
+			return false
+		}
+
+		rest = rest[pos+len(arg):]
+	}
+
+	return true
+}
+
+func (conf *ProcessFilter) processInfo(proc *process.Process) (*ProcessInfo, error) {
+	var err error
+
+	info := &ProcessInfo{PID: proc.Pid, Process: proc}
+
+	if info.Name, err = proc.Name(); err != nil {
+		return nil, err
+	}
+
+	if conf.match != nil {
+		if info.Cmdline, err = proc.Cmdline(); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(conf.user) > 0 {
+		if info.User, err = proc.Username(); err != nil {
+			return nil, err
+		}
+	}
+
+	return info, nil
+}
+
+func isZombie(proc *process.Process) bool {
+	status, err := proc.Status()
+	if err != nil {
+		return false
+	}
+
+	for _, item := range status {
+		if item == process.Zombie {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,140 @@
+package measurements
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestProcessFilter_Selected(t *testing.T) {
+	info := &ProcessInfo{
+		PID:     1234,
+		Name:    "nginx",
+		Cmdline: "nginx: master process /usr/sbin/nginx -g daemon on;",
+		User:    "root",
+	}
+	tests := []struct {
+		name   string
+		filter ProcessFilter
+		want   bool
+	}{
+		{"name", ProcessFilter{name: "nginx"}, true},
+		{"other name", ProcessFilter{name: "nginx: master"}, false},
+		{"cmdline", ProcessFilter{matchS: "master process"}, true},
+		{"other cmdline", ProcessFilter{matchS: "^/usr/sbin/nginx"}, false},
+		{"user", ProcessFilter{user: "root"}, true},
+		{"other user", ProcessFilter{user: "www-data"}, false},
+		{"pidfile", ProcessFilter{pidfile: "testdata/process/valid.pid"}, true},
+		{"all criteria", ProcessFilter{name: "nginx", matchS: "master", user: "root"}, true},
+		{"one criterion fails", ProcessFilter{name: "nginx", matchS: "worker", user: "root"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := tt.filter
+			if err := filter.Check(); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := filter.Selected(info); got != tt.want {
+				t.Errorf("Selected() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessFilter_Check(t *testing.T) {
+	tests := []struct {
+		name    string
+		filter  ProcessFilter
+		wantErr bool
+	}{
+		{"no criteria", ProcessFilter{}, true},
+		{"invalid regexp", ProcessFilter{matchS: "("}, true},
+		{"invalid pidfile", ProcessFilter{pidfile: "testdata/process/invalid.pid"}, true},
+		{"missing pidfile", ProcessFilter{pidfile: "testdata/process/missing.pid"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := tt.filter
+			if err := filter.Check(); (err != nil) != tt.wantErr {
+				t.Errorf("Check() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestReadPidfile(t *testing.T) {
+	pid, err := ReadPidfile("testdata/process/valid.pid")
+	if err != nil || pid != 1234 {
+		t.Errorf("ReadPidfile() = %d, %v, want 1234", pid, err)
+	}
+
+	if _, err := ReadPidfile("testdata/process/missing.pid"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("ReadPidfile() error = %v, want not exist", err)
+	}
+}
+
+func TestProcessFilter_Processes(t *testing.T) {
+	// the test's parent is the go tool, which doesn't wrap the test's
+	// arguments, therefore it's a regular process to be monitored
+	tests := []struct {
+		name string
+		pid  int
+		want int
+	}{
+		{"self", os.Getpid(), 0},
+		{"parent", os.Getppid(), 1},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			pidfile := filepath.Join(t.TempDir(), "check.pid")
+			if err := os.WriteFile(pidfile, []byte(strconv.Itoa(tt.pid)), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			filter := ProcessFilter{pidfile: pidfile}
+			if err := filter.Check(); err != nil {
+				t.Fatal(err)
+			}
+
+			procs, err := filter.Processes()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(procs) != tt.want {
+				t.Errorf("Processes() = %v, want %d processes", procs, tt.want)
+			}
+		})
+	}
+}
+
+func TestWraps(t *testing.T) {
+	args := []string{"/usr/bin/sensu-base-checks", "process", "--match", "foo bar"}
+	tests := []struct {
+		name    string
+		cmdline string
+		want    bool
+	}{
+		{"shell wrapper", `sh -c sensu-base-checks process --match "foo bar"`, true},
+		{"shell wrapper with full path", "/bin/sh -c /usr/bin/sensu-base-checks process --match 'foo bar'", true},
+		{"agent", "/usr/sbin/sensu-agent start", false},
+		{"other arguments", "sh -c sensu-base-checks process --name foo", false},
+		{"arguments out of order", `sh -c sensu-base-checks --match "foo bar" process`, false},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			if got := wraps(tt.cmdline, args); got != tt.want {
+				t.Errorf("wraps() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
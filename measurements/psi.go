@@ -0,0 +1,113 @@
+package measurements
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// PressureStat contains pressure stall information of a single line
+type PressureStat struct {
+	Avg10  float64
+	Avg60  float64
+	Avg300 float64
+	Total  uint64 // in microseconds
+}
+
+// Averages returns avg10, avg60, and avg300 values, in this order
+func (stat *PressureStat) Averages() []float64 {
+	return []float64{stat.Avg10, stat.Avg60, stat.Avg300}
+}
+
+// Pressure contains pressure stall information of a resource. Full is nil
+// if it is not provided (like for CPU on older kernels).
+type Pressure struct {
+	Some *PressureStat
+	Full *PressureStat
+}
+
+// ReadPressure reads a PSI file (like /proc/pressure/io, or io.pressure of a cgroup)
+func ReadPressure(path string) (*Pressure, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	pressure, err := parsePressure(file)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+
+	return pressure, nil
+}
+
+func parsePressure(reader io.Reader) (*Pressure, error) {
+	pressure := &Pressure{}
+
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		stat, err := parsePressureStat(fields[1:])
+		if err != nil {
+			return nil, err
+		}
+
+		switch fields[0] {
+		case "some":
+			pressure.Some = stat
+		case "full":
+			pressure.Full = stat
+		default:
+			return nil, fmt.Errorf("unknown line %q", fields[0])
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if pressure.Some == nil {
+		return nil, fmt.Errorf("no \"some\" line found")
+	}
+
+	return pressure, nil
+}
+
+func parsePressureStat(fields []string) (*PressureStat, error) {
+	stat := &PressureStat{}
+
+	for _, field := range fields {
+		items := strings.SplitN(field, "=", 2)
+		if len(items) != 2 {
+			return nil, fmt.Errorf("invalid field %q", field)
+		}
+
+		var err error
+
+		switch items[0] {
+		case "avg10":
+			stat.Avg10, err = strconv.ParseFloat(items[1], 64)
+		case "avg60":
+			stat.Avg60, err = strconv.ParseFloat(items[1], 64)
+		case "avg300":
+			stat.Avg300, err = strconv.ParseFloat(items[1], 64)
+		case "total":
+			stat.Total, err = strconv.ParseUint(items[1], 10, 64)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid field %q: %w", field, err)
+		}
+	}
+
+	return stat, nil
+}
@@ -0,0 +1,55 @@
+package measurements
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-test/deep"
+)
+
+func TestReadPressure(t *testing.T) {
+	tests := []struct {
+		name string
+		want *Pressure
+	}{
+		{
+			"cpu",
+			&Pressure{Some: &PressureStat{Avg10: 1.53, Avg60: 0.87, Avg300: 0.45, Total: 123456789}},
+		},
+		{
+			"memory",
+			&Pressure{
+				Some: &PressureStat{Avg10: 12, Avg60: 8.5, Avg300: 2.25, Total: 9876543},
+				Full: &PressureStat{Avg10: 4.1, Avg60: 2, Avg300: 0.5, Total: 1234567},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadPressure(filepath.Join("testdata", "pressure", tt.name))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := deep.Equal(tt.want, got); diff != nil {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestParsePressure_invalid(t *testing.T) {
+	for _, input := range []string{
+		"",
+		"full avg10=0.00 avg60=0.00 avg300=0.00 total=0\n",
+		"some avg10=x avg60=0.00 avg300=0.00 total=0\n",
+		"some avg10 avg60=0.00\n",
+		"other avg10=0.00\n",
+	} {
+		if _, err := parsePressure(strings.NewReader(input)); err == nil {
+			t.Errorf("parsePressure(%q) succeeded", input)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package measurements
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// RedirectPolicy follows at most Max redirects, and records the redirect
+// chain. It stops on redirect loops and on downgrades from https to http.
+type RedirectPolicy struct {
+	Max   int
+	Chain []string
+}
+
+// CheckRedirect implements http.Client's CheckRedirect. It returns
+// http.ErrUseLastResponse if following redirects is disabled.
+func (policy *RedirectPolicy) CheckRedirect(req *http.Request, via []*http.Request) error {
+	if policy.Max == 0 {
+		return http.ErrUseLastResponse
+	}
+
+	if len(policy.Chain) == 0 {
+		policy.Chain = append(policy.Chain, via[0].URL.String())
+	}
+
+	target := req.URL.String()
+	policy.Chain = append(policy.Chain, target)
+	chain := policy.String()
+
+	for _, prev := range via {
+		if prev.URL.String() == target {
+			return fmt.Errorf("redirect loop: %s", chain)
+		}
+	}
+
+	if via[len(via)-1].URL.Scheme == "https" && req.URL.Scheme == "http" {
+		return fmt.Errorf("redirect downgrades from https to http: %s", chain)
+	}
+
+	if len(via) > policy.Max {
+		return fmt.Errorf("more than %d redirects: %s", policy.Max, chain)
+	}
+
+	return nil
+}
+
+// Redirects returns the number of followed redirects
+func (policy *RedirectPolicy) Redirects() int {
+	if len(policy.Chain) == 0 {
+		return 0
+	}
+
+	return len(policy.Chain) - 1
+}
+
+// String returns the redirect chain in human readable form
+func (policy *RedirectPolicy) String() string {
+	return strings.Join(policy.Chain, " -> ")
+}
@@ -0,0 +1,83 @@
+package measurements
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// redirectServer redirects /hopN to /hop(N-1), /hop0 to target, and serves
+// anything else
+func redirectServer(target string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/loop":
+			http.Redirect(w, r, "/loop-back", http.StatusFound)
+		case r.URL.Path == "/loop-back":
+			http.Redirect(w, r, "/loop", http.StatusFound)
+		case r.URL.Path == "/hop0":
+			http.Redirect(w, r, target, http.StatusFound)
+		case strings.HasPrefix(r.URL.Path, "/hop"):
+			prev := r.URL.Path[len("/hop"):]
+			http.Redirect(w, r, "/hop"+string(prev[0]-1), http.StatusMovedPermanently)
+		default:
+			_, _ = w.Write([]byte("ok"))
+		}
+	}
+}
+
+func TestRedirectPolicy_CheckRedirect(t *testing.T) {
+	plain := httptest.NewServer(redirectServer("/final"))
+	defer plain.Close()
+
+	secure := httptest.NewTLSServer(redirectServer(plain.URL + "/final"))
+	defer secure.Close()
+
+	tests := []struct {
+		name      string
+		url       string
+		max       int
+		wantErr   string
+		wantFinal string
+		redirects int
+	}{
+		{"disabled", plain.URL + "/hop1", 0, "", plain.URL + "/hop1", 0},
+		{"within limit", plain.URL + "/hop2", 3, "", plain.URL + "/final", 3},
+		{"over limit", plain.URL + "/hop2", 2, "more than 2 redirects", "", 3},
+		{"loop", plain.URL + "/loop", 5, "redirect loop", "", 2},
+		{"downgrade", secure.URL + "/hop0", 5, "redirect downgrades from https to http", "", 1},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			policy := &RedirectPolicy{Max: tt.max}
+			client := secure.Client()
+			client.CheckRedirect = policy.CheckRedirect
+
+			resp, err := client.Get(tt.url)
+
+			switch {
+			case len(tt.wantErr) == 0 && err != nil:
+				t.Fatalf("Get() error = %v, wanted none", err)
+			case len(tt.wantErr) > 0 && err == nil:
+				t.Fatalf("Get() error = nil, wanted %q", tt.wantErr)
+			case len(tt.wantErr) > 0 && !strings.Contains(err.Error(), tt.wantErr):
+				t.Fatalf("Get() error = %v, wanted %q", err, tt.wantErr)
+			}
+
+			if err == nil {
+				resp.Body.Close()
+
+				if final := resp.Request.URL.String(); final != tt.wantFinal {
+					t.Errorf("final URL = %s, want %s", final, tt.wantFinal)
+				}
+			}
+
+			if got := policy.Redirects(); got != tt.redirects {
+				t.Errorf("Redirects() = %d, want %d (%s)", got, tt.redirects, policy)
+			}
+		})
+	}
+}
@@ -0,0 +1,376 @@
+package measurements
+
+import (
+	"bufio"
+	"encoding/asn1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strings"
+)
+
+// ldapStartTLSOID is the LDAP StartTLS extended operation (RFC 4511)
+const ldapStartTLSOID = "1.3.6.1.4.1.1466.20037"
+
+// postgresSSLRequest is the PostgreSQL SSLRequest code
+const postgresSSLRequest = 80877103
+
+// MySQL capability flags
+const (
+	mysqlClientProtocol41       = 0x00000200
+	mysqlClientSSL              = 0x00000800
+	mysqlClientSecureConnection = 0x00008000
+)
+
+// StartTLSLineLimit is the maximum length of lines read from text protocol
+// servers
+const StartTLSLineLimit = 4096
+
+var startTLSProtocols = map[string]func(net.Conn, string) error{
+	"imap":     startTLSIMAP,
+	"ldap":     startTLSLDAP,
+	"mysql":    startTLSMySQL,
+	"pop3":     startTLSPOP3,
+	"postgres": startTLSPostgres,
+	"smtp":     startTLSSMTP,
+}
+
+// StartTLSProtocols returns protocols supported by StartTLS
+func StartTLSProtocols() []string {
+	protocols := make([]string, 0, len(startTLSProtocols))
+	for protocol := range startTLSProtocols {
+		protocols = append(protocols, protocol)
+	}
+
+	sort.Strings(protocols)
+
+	return protocols
+}
+
+// StartTLS upgrades a plain text connection of protocol to be ready for a TLS
+// handshake. Hostname is used for identifying the client where the protocol
+// requires it (SMTP's EHLO).
+func StartTLS(conn net.Conn, protocol, hostname string) error {
+	fn, ok := startTLSProtocols[protocol]
+	if !ok {
+		return fmt.Errorf("unknown STARTTLS protocol %q", protocol)
+	}
+
+	if err := fn(conn, hostname); err != nil {
+		return fmt.Errorf("%s STARTTLS: %w", protocol, err)
+	}
+
+	return nil
+}
+
+// readLine reads a CRLF or LF terminated line
+func readLine(reader *bufio.Reader) (string, error) {
+	var line []byte
+
+	for {
+		chunk, isPrefix, err := reader.ReadLine()
+		if err != nil {
+			return "", err
+		}
+
+		line = append(line, chunk...)
+		if len(line) > StartTLSLineLimit {
+			return "", errors.New("line too long")
+		}
+
+		if !isPrefix {
+			return string(line), nil
+		}
+	}
+}
+
+// readSMTPReply reads a (possibly multiline) SMTP reply, returning its lines
+// if it has the expected code
+func readSMTPReply(reader *bufio.Reader, code string) ([]string, error) {
+	var lines []string
+
+	for {
+		line, err := readLine(reader)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(line) < 3 || line[:3] != code {
+			return nil, fmt.Errorf("unexpected reply %q", line)
+		}
+
+		lines = append(lines, line)
+
+		if len(line) == 3 || line[3] != '-' {
+			return lines, nil
+		}
+	}
+}
+
+func startTLSSMTP(conn net.Conn, hostname string) error {
+	reader := bufio.NewReader(conn)
+
+	if _, err := readSMTPReply(reader, "220"); err != nil {
+		return fmt.Errorf("greeting: %w", err)
+	}
+
+	if _, err := fmt.Fprintf(conn, "EHLO %s\r\n", hostname); err != nil {
+		return err
+	}
+
+	lines, err := readSMTPReply(reader, "250")
+	if err != nil {
+		return fmt.Errorf("EHLO: %w", err)
+	}
+
+	supported := false
+
+	for _, line := range lines {
+		if len(line) > 4 && strings.EqualFold(strings.TrimSpace(line[4:]), "STARTTLS") {
+			supported = true
+		}
+	}
+
+	if !supported {
+		return errors.New("not supported by server")
+	}
+
+	if _, err := io.WriteString(conn, "STARTTLS\r\n"); err != nil {
+		return err
+	}
+
+	if _, err := readSMTPReply(reader, "220"); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func startTLSIMAP(conn net.Conn, _ string) error {
+	reader := bufio.NewReader(conn)
+
+	line, err := readLine(reader)
+	if err != nil {
+		return fmt.Errorf("greeting: %w", err)
+	}
+
+	if !strings.HasPrefix(line, "* OK") {
+		return fmt.Errorf("unexpected greeting %q", line)
+	}
+
+	if _, err := io.WriteString(conn, "a001 STARTTLS\r\n"); err != nil {
+		return err
+	}
+
+	for {
+		line, err := readLine(reader)
+		if err != nil {
+			return err
+		}
+
+		// untagged responses may precede the tagged one
+		if strings.HasPrefix(line, "* ") {
+			continue
+		}
+
+		if !strings.HasPrefix(line, "a001 OK") {
+			return fmt.Errorf("unexpected reply %q", line)
+		}
+
+		return nil
+	}
+}
+
+func startTLSPOP3(conn net.Conn, _ string) error {
+	reader := bufio.NewReader(conn)
+
+	line, err := readLine(reader)
+	if err != nil {
+		return fmt.Errorf("greeting: %w", err)
+	}
+
+	if !strings.HasPrefix(line, "+OK") {
+		return fmt.Errorf("unexpected greeting %q", line)
+	}
+
+	if _, err := io.WriteString(conn, "STLS\r\n"); err != nil {
+		return err
+	}
+
+	line, err = readLine(reader)
+	if err != nil {
+		return err
+	}
+
+	if !strings.HasPrefix(line, "+OK") {
+		return fmt.Errorf("unexpected reply %q", line)
+	}
+
+	return nil
+}
+
+// ldapExtendedRequest is an LDAP ExtendedRequest message
+type ldapExtendedRequest struct {
+	MessageID int
+	Request   struct {
+		Name []byte `asn1:"tag:0"`
+	} `asn1:"application,tag:23"`
+}
+
+// ldapExtendedResponse is the beginning of an LDAP ExtendedResponse message
+type ldapExtendedResponse struct {
+	MessageID int
+	Response  asn1.RawValue
+}
+
+func startTLSLDAP(conn net.Conn, _ string) error {
+	req := ldapExtendedRequest{MessageID: 1}
+	req.Request.Name = []byte(ldapStartTLSOID)
+
+	msg, err := asn1.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	if _, err := conn.Write(msg); err != nil {
+		return err
+	}
+
+	msg, err = readBER(conn)
+	if err != nil {
+		return err
+	}
+
+	var resp ldapExtendedResponse
+	if _, err := asn1.Unmarshal(msg, &resp); err != nil {
+		return fmt.Errorf("invalid response: %w", err)
+	}
+
+	if resp.Response.Class != asn1.ClassApplication || resp.Response.Tag != 24 {
+		return fmt.Errorf("unexpected response with tag %d", resp.Response.Tag)
+	}
+
+	var result asn1.RawValue
+	if _, err := asn1.Unmarshal(resp.Response.Bytes, &result); err != nil {
+		return fmt.Errorf("invalid response: %w", err)
+	}
+
+	if result.Tag != asn1.TagEnum || len(result.Bytes) != 1 {
+		return errors.New("invalid result code")
+	}
+
+	if result.Bytes[0] != 0 {
+		return fmt.Errorf("server responded with result code %d", result.Bytes[0])
+	}
+
+	return nil
+}
+
+// readBER reads a single BER encoded element with definite length
+func readBER(reader io.Reader) ([]byte, error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return nil, err
+	}
+
+	length := int(header[1])
+
+	if length&0x80 != 0 {
+		octets := length & 0x7f
+		if octets == 0 || octets > 3 {
+			return nil, errors.New("unsupported BER length")
+		}
+
+		lengthBytes := make([]byte, octets)
+		if _, err := io.ReadFull(reader, lengthBytes); err != nil {
+			return nil, err
+		}
+
+		header = append(header, lengthBytes...)
+		length = 0
+
+		for _, octet := range lengthBytes {
+			length = length<<8 | int(octet)
+		}
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(reader, body); err != nil {
+		return nil, err
+	}
+
+	return append(header, body...), nil
+}
+
+func startTLSPostgres(conn net.Conn, _ string) error {
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint32(msg[0:4], 8)
+	binary.BigEndian.PutUint32(msg[4:8], postgresSSLRequest)
+
+	if _, err := conn.Write(msg); err != nil {
+		return err
+	}
+
+	resp := make([]byte, 1)
+	if _, err := io.ReadFull(conn, resp); err != nil {
+		return err
+	}
+
+	switch resp[0] {
+	case 'S':
+		return nil
+	case 'N':
+		return errors.New("not supported by server")
+	}
+
+	return fmt.Errorf("unexpected response %q", resp[0])
+}
+
+func startTLSMySQL(conn net.Conn, _ string) error {
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return fmt.Errorf("handshake: %w", err)
+	}
+
+	length := int(header[0]) | int(header[1])<<8 | int(header[2])<<16
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(conn, payload); err != nil {
+		return fmt.Errorf("handshake: %w", err)
+	}
+
+	if len(payload) == 0 || payload[0] == 0xff {
+		return errors.New("server responded with error")
+	}
+
+	// protocol version, server version, connection ID, auth data, filler
+	idx := strings.IndexByte(string(payload[1:]), 0)
+	capOffset := 1 + idx + 1 + 4 + 8 + 1
+
+	if idx < 0 || len(payload) < capOffset+2 {
+		return errors.New("invalid handshake")
+	}
+
+	if binary.LittleEndian.Uint16(payload[capOffset:])&mysqlClientSSL == 0 {
+		return errors.New("not supported by server")
+	}
+
+	msg := make([]byte, 4+32)
+	msg[0] = 32
+	msg[3] = header[3] + 1
+	binary.LittleEndian.PutUint32(
+		msg[4:8],
+		mysqlClientSSL|mysqlClientProtocol41|mysqlClientSecureConnection,
+	)
+	binary.LittleEndian.PutUint32(msg[8:12], 1<<24)
+	msg[12] = 0x21 // utf8_general_ci
+
+	if _, err := conn.Write(msg); err != nil {
+		return err
+	}
+
+	return nil
+}
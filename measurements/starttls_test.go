@@ -0,0 +1,152 @@
+package measurements
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// scriptedServer answers client lines with predefined replies
+func scriptedServer(greeting string, replies ...string) func(net.Conn) {
+	return func(conn net.Conn) {
+		reader := bufio.NewReader(conn)
+
+		if len(greeting) > 0 {
+			_, _ = io.WriteString(conn, greeting)
+		}
+
+		for _, reply := range replies {
+			if _, err := reader.ReadString('\n'); err != nil {
+				return
+			}
+
+			_, _ = io.WriteString(conn, reply)
+		}
+	}
+}
+
+func ldapServer(resultCode byte) func(net.Conn) {
+	return func(conn net.Conn) {
+		if _, err := readBER(conn); err != nil {
+			return
+		}
+
+		// LDAPMessage { messageID 1, extendedResp { resultCode, matchedDN "", diagnosticMessage "" } }
+		_, _ = conn.Write([]byte{0x30, 0x0c, 0x02, 0x01, 0x01, 0x78, 0x07, 0x0a, 0x01, resultCode, 0x04, 0x00, 0x04, 0x00})
+	}
+}
+
+func postgresServer(answer byte) func(net.Conn) {
+	return func(conn net.Conn) {
+		msg := make([]byte, 8)
+		if _, err := io.ReadFull(conn, msg); err != nil {
+			return
+		}
+
+		if binary.BigEndian.Uint32(msg[4:]) != postgresSSLRequest {
+			return
+		}
+
+		_, _ = conn.Write([]byte{answer})
+	}
+}
+
+func mysqlServer(capabilities uint16) func(net.Conn) {
+	return func(conn net.Conn) {
+		payload := []byte{10}
+		payload = append(payload, "8.0.28\x00"...)
+		payload = append(payload, 1, 0, 0, 0)    // connection ID
+		payload = append(payload, "abcdefgh"...) // auth data
+		payload = append(payload, 0, 0, 0)       // filler, capabilities placeholder
+		binary.LittleEndian.PutUint16(payload[len(payload)-2:], capabilities)
+
+		header := []byte{byte(len(payload)), 0, 0, 0}
+		_, _ = conn.Write(append(header, payload...))
+
+		msg := make([]byte, 36)
+		_, _ = io.ReadFull(conn, msg)
+	}
+}
+
+func TestStartTLS(t *testing.T) {
+	tests := []struct {
+		name     string
+		protocol string
+		server   func(net.Conn)
+		wantErr  string
+	}{
+		{
+			"smtp",
+			"smtp",
+			scriptedServer("220-mail.example.com ESMTP\r\n220 ready\r\n",
+				"250-mail.example.com\r\n250-PIPELINING\r\n250 STARTTLS\r\n", "220 go ahead\r\n"),
+			"",
+		},
+		{
+			"smtp without STARTTLS",
+			"smtp",
+			scriptedServer("220 mail.example.com ESMTP\r\n", "250-mail.example.com\r\n250 PIPELINING\r\n"),
+			"not supported",
+		},
+		{
+			"smtp rejected",
+			"smtp",
+			scriptedServer("220 mail.example.com ESMTP\r\n", "250 STARTTLS\r\n", "454 TLS not available\r\n"),
+			"unexpected reply",
+		},
+		{
+			"imap",
+			"imap",
+			scriptedServer("* OK IMAP4rev1 ready\r\n", "* CAPABILITY IMAP4rev1\r\na001 OK Begin TLS negotiation\r\n"),
+			"",
+		},
+		{
+			"imap rejected",
+			"imap",
+			scriptedServer("* OK IMAP4rev1 ready\r\n", "a001 BAD unknown command\r\n"),
+			"unexpected reply",
+		},
+		{"pop3", "pop3", scriptedServer("+OK POP3 ready\r\n", "+OK begin TLS\r\n"), ""},
+		{"pop3 rejected", "pop3", scriptedServer("+OK POP3 ready\r\n", "-ERR unknown command\r\n"), "unexpected reply"},
+		{"ldap", "ldap", ldapServer(0), ""},
+		{"ldap rejected", "ldap", ldapServer(2), "result code 2"},
+		{"postgres", "postgres", postgresServer('S'), ""},
+		{"postgres rejected", "postgres", postgresServer('N'), "not supported"},
+		{"mysql", "mysql", mysqlServer(0xffff), ""},
+		{"mysql without SSL", "mysql", mysqlServer(0xf7ff), "not supported"},
+		{"unknown protocol", "gopher", scriptedServer(""), "unknown STARTTLS protocol"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			client, server := net.Pipe()
+
+			defer client.Close()
+
+			go func() {
+				defer server.Close()
+				tt.server(server)
+			}()
+
+			if err := client.SetDeadline(time.Now().Add(time.Second)); err != nil {
+				t.Fatal(err)
+			}
+
+			err := StartTLS(client, tt.protocol, "localhost")
+
+			switch {
+			case len(tt.wantErr) == 0 && err != nil:
+				t.Errorf("StartTLS() error = %v, wanted none", err)
+			case len(tt.wantErr) > 0 && err == nil:
+				t.Errorf("StartTLS() error = nil, wanted %q", tt.wantErr)
+			case len(tt.wantErr) > 0 && !strings.Contains(err.Error(), tt.wantErr):
+				t.Errorf("StartTLS() error = %v, wanted %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package measurements
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+type stateFile struct {
+	Time time.Time       `json:"time"`
+	Data json.RawMessage `json:"data"`
+}
+
+// LoadState reads data saved by SaveState into v, and returns the time it was saved
+func LoadState(path string, v interface{}) (time.Time, error) {
+	var state stateFile
+
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if err := json.Unmarshal(contents, &state); err != nil {
+		return time.Time{}, fmt.Errorf("parsing state file %s: %w", path, err)
+	}
+
+	if err := json.Unmarshal(state.Data, v); err != nil {
+		return time.Time{}, fmt.Errorf("parsing state file %s: %w", path, err)
+	}
+
+	return state.Time, nil
+}
+
+// SaveState writes v into a state file atomically, with a timestamp
+func SaveState(path string, timestamp time.Time, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	contents, err := json.Marshal(stateFile{Time: timestamp, Data: data})
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("writing state file: %w", err)
+	}
+
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(contents); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing state file: %w", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing state file: %w", err)
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,34 @@
+//go:build linux
+// +build linux
+
+package measurements
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// readOnly checks superblock flags too, as a filesystem remounted read-only
+// on errors keeps its "rw" mount option.
+func readOnly(mountpoint string) bool {
+	var st unix.Statfs_t
+
+	if err := unix.Statfs(mountpoint, &st); err != nil {
+		return false
+	}
+
+	return st.Flags&unix.ST_RDONLY != 0
+}
+
+// filesystemID returns the filesystem ID of a mount point, or an empty string
+// if it cannot be read
+func filesystemID(mountpoint string) string {
+	var st unix.Statfs_t
+
+	if err := unix.Statfs(mountpoint, &st); err != nil {
+		return ""
+	}
+
+	return fmt.Sprintf("%08x%08x", uint32(st.Fsid.Val[0]), uint32(st.Fsid.Val[1]))
+}
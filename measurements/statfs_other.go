@@ -0,0 +1,12 @@
+//go:build !linux
+// +build !linux
+
+package measurements
+
+func readOnly(mountpoint string) bool {
+	return false
+}
+
+func filesystemID(mountpoint string) string {
+	return ""
+}
@@ -0,0 +1,99 @@
+package measurements
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"time"
+)
+
+// TCPBannerLimit is the maximum number of bytes read while matching a banner
+const TCPBannerLimit = 64 * 1024
+
+// ErrBannerMismatch is returned when received data doesn't match the
+// expected banner
+var ErrBannerMismatch = errors.New("banner does not match")
+
+// TCPProbe connects to a TCP service, optionally sends a payload, and matches
+// received data against an expected banner
+type TCPProbe struct {
+	Network string
+	Timeout time.Duration
+	Payload []byte
+	Expect  *regexp.Regexp
+}
+
+// Probe connects to address, and fills tracer with connection timings. It
+// returns received data, if banner is expected.
+func (p *TCPProbe) Probe(address string, tracer *TCPTracer) ([]byte, error) {
+	network := p.Network
+	if len(network) == 0 {
+		network = "tcp"
+	}
+
+	tracer.ConnStart = time.Now()
+
+	conn, err := net.DialTimeout(network, address, p.Timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	defer conn.Close()
+
+	tracer.ConnDone = time.Now()
+
+	if err := conn.SetDeadline(tracer.ConnStart.Add(p.Timeout)); err != nil {
+		return nil, err
+	}
+
+	if len(p.Payload) > 0 {
+		if _, err := conn.Write(p.Payload); err != nil {
+			return nil, fmt.Errorf("cannot send payload: %w", err)
+		}
+	}
+
+	if p.Expect == nil {
+		tracer.Done()
+
+		return nil, nil
+	}
+
+	banner, err := p.readBanner(conn, tracer)
+
+	tracer.Done()
+
+	return banner, err
+}
+
+func (p *TCPProbe) readBanner(conn net.Conn, tracer *TCPTracer) ([]byte, error) {
+	banner := &bytes.Buffer{}
+	buf := make([]byte, 4096)
+
+	for banner.Len() < TCPBannerLimit {
+		n, err := conn.Read(buf)
+		if n > 0 {
+			if tracer.StartResponding.IsZero() {
+				tracer.StartResponding = time.Now()
+			}
+
+			banner.Write(buf[:n])
+
+			if p.Expect.Match(banner.Bytes()) {
+				return banner.Bytes(), nil
+			}
+		}
+
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return banner.Bytes(), fmt.Errorf("%w %q: %v", ErrBannerMismatch, p.Expect, err)
+		}
+	}
+
+	return banner.Bytes(), fmt.Errorf("%w %q", ErrBannerMismatch, p.Expect)
+}
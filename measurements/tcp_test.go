@@ -0,0 +1,82 @@
+package measurements
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"regexp"
+	"testing"
+	"time"
+)
+
+// tcpServer greets clients with a banner, and answers PING with PONG
+func tcpServer(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer conn.Close()
+
+				_, _ = conn.Write([]byte("220 test ready\r\n"))
+
+				line, err := bufio.NewReader(conn).ReadString('\n')
+				if err == nil && line == "PING\r\n" {
+					_, _ = conn.Write([]byte("+PONG\r\n"))
+				}
+			}()
+		}
+	}()
+
+	return listener.Addr().String()
+}
+
+func TestTCPProbe_Probe(t *testing.T) {
+	address := tcpServer(t)
+	tests := []struct {
+		name    string
+		payload string
+		expect  string
+		wantErr bool
+	}{
+		{"connect only", "", "", false},
+		{"banner", "", "^220 ", false},
+		{"banner mismatch", "QUIT\r\n", "^\\+PONG", true},
+		{"payload", "PING\r\n", "\\+PONG\r\n", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			probe := &TCPProbe{Timeout: 2 * time.Second, Payload: []byte(tt.payload)}
+			if len(tt.expect) > 0 {
+				probe.Expect = regexp.MustCompile(tt.expect)
+			}
+
+			now := time.Now()
+			tracer := NewTCPTracer(now, now)
+
+			_, err := probe.Probe(address, tracer)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Probe() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr && !errors.Is(err, ErrBannerMismatch) {
+				t.Errorf("Probe() error = %v, want ErrBannerMismatch", err)
+			}
+
+			if !tt.wantErr && (tracer.Connect() <= 0 || tracer.Total() < tracer.Connect()) {
+				t.Errorf("Probe() timings: connect %v, total %v", tracer.Connect(), tracer.Total())
+			}
+		})
+	}
+}
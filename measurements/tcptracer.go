@@ -0,0 +1,46 @@
+package measurements
+
+import (
+	"time"
+)
+
+// TCPTracer collects timings of a TCP connection, similar to HTTPTracer.
+// Name resolution is shared between connections to all resolved addresses,
+// therefore durations are calculated from the start of the connection
+// attempt, plus name lookup time.
+type TCPTracer struct {
+	DNSStart        time.Time
+	DNSDone         time.Time
+	ConnStart       time.Time
+	ConnDone        time.Time
+	StartResponding time.Time
+	Finished        time.Time
+}
+
+// NewTCPTracer returns a tracer for a connection attempt, with name lookup
+// timings
+func NewTCPTracer(dnsStart, dnsDone time.Time) *TCPTracer {
+	return &TCPTracer{DNSStart: dnsStart, DNSDone: dnsDone}
+}
+
+func (t *TCPTracer) Done() {
+	if t != nil {
+		t.Finished = time.Now()
+	}
+}
+
+func (t *TCPTracer) Total() time.Duration {
+	return t.Namelookup() + t.Finished.Sub(t.ConnStart)
+}
+
+func (t *TCPTracer) Namelookup() time.Duration {
+	return t.DNSDone.Sub(t.DNSStart)
+}
+
+func (t *TCPTracer) Connect() time.Duration {
+	return t.Namelookup() + t.ConnDone.Sub(t.ConnStart)
+}
+
+func (t *TCPTracer) Starttransfer() time.Duration {
+	return t.Namelookup() + t.StartResponding.Sub(t.ConnStart)
+}
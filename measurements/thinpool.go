@@ -0,0 +1,112 @@
+package measurements
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ThinPool contains allocation information of an LVM thin pool
+type ThinPool struct {
+	Name            string // in vg/lv form
+	DataPercent     float64
+	MetadataPercent float64
+}
+
+// ThinPools reads LVM thin pools using the lvs command. It returns thin pools
+// by name, and by device paths of their thin volumes.
+func ThinPools(run CommandRunner, lvs []string) (map[string]*ThinPool, map[string]*ThinPool, error) {
+	args := append(
+		append([]string{}, lvs[1:]...),
+		"--noheadings",
+		"--separator", "|",
+		"-o", "vg_name,lv_name,lv_attr,pool_lv,data_percent,metadata_percent",
+	)
+
+	out, err := run(lvs[0], args...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return parseLvs(out)
+}
+
+func parseLvs(out []byte) (map[string]*ThinPool, map[string]*ThinPool, error) {
+	type thinVolume struct{ vg, lv, pool string }
+
+	pools := map[string]*ThinPool{}
+	volumes := []thinVolume{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 {
+			continue
+		}
+
+		fields := strings.Split(line, "|")
+		if len(fields) != 6 {
+			return nil, nil, fmt.Errorf("parsing lvs: unexpected line %q", line)
+		}
+
+		for i := range fields {
+			fields[i] = strings.TrimSpace(fields[i])
+		}
+
+		vg, lv, attr := fields[0], fields[1], fields[2]
+
+		switch {
+		case strings.HasPrefix(attr, "t"):
+			pool := &ThinPool{Name: vg + "/" + lv}
+
+			for _, item := range []struct {
+				source string
+				target *float64
+			}{
+				{fields[4], &pool.DataPercent},
+				{fields[5], &pool.MetadataPercent},
+			} {
+				// lvs leaves usage empty for inactive pools
+				if len(item.source) == 0 {
+					continue
+				}
+
+				val, err := strconv.ParseFloat(strings.ReplaceAll(item.source, ",", "."), 64)
+				if err != nil {
+					return nil, nil, fmt.Errorf("parsing lvs of %s: %w", pool.Name, err)
+				}
+
+				*item.target = val
+			}
+
+			pools[pool.Name] = pool
+		case strings.HasPrefix(attr, "V"):
+			volumes = append(volumes, thinVolume{vg: vg, lv: lv, pool: fields[3]})
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	byDevice := map[string]*ThinPool{}
+
+	for _, vol := range volumes {
+		pool, ok := pools[vol.vg+"/"+vol.pool]
+		if !ok {
+			continue
+		}
+
+		byDevice[fmt.Sprintf("/dev/%s/%s", vol.vg, vol.lv)] = pool
+		byDevice[fmt.Sprintf("/dev/mapper/%s-%s", dmEscape(vol.vg), dmEscape(vol.lv))] = pool
+	}
+
+	return pools, byDevice, nil
+}
+
+// dmEscape escapes VG and LV names for device mapper names
+func dmEscape(name string) string {
+	return strings.ReplaceAll(name, "-", "--")
+}
@@ -0,0 +1,41 @@
+package measurements
+
+import (
+	"testing"
+
+	"github.com/go-test/deep"
+)
+
+func TestThinPools(t *testing.T) {
+	run := fixtureRunner(t, map[string]string{"--noheadings": "lvs.txt"})
+
+	pools, byDevice, err := ThinPools(run, []string{"lvs"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantPools := map[string]*ThinPool{
+		"archive/cold-pool":  {Name: "archive/cold-pool"},
+		"data/pool0":         {Name: "data/pool0", DataPercent: 81.52, MetadataPercent: 23.05},
+		"vg-sys/docker-pool": {Name: "vg-sys/docker-pool", DataPercent: 12, MetadataPercent: 96.8},
+	}
+
+	if diff := deep.Equal(wantPools, pools); diff != nil {
+		t.Error(diff)
+	}
+
+	for dev, want := range map[string]string{
+		"/dev/data/vol-a":                  "data/pool0",
+		"/dev/mapper/data-vol--b":          "data/pool0",
+		"/dev/mapper/vg--sys-container--1": "vg-sys/docker-pool",
+		"/dev/vg-sys/container-1":          "vg-sys/docker-pool",
+	} {
+		if pool, ok := byDevice[dev]; !ok || pool.Name != want {
+			t.Errorf("pool of %s = %v, want %s", dev, pool, want)
+		}
+	}
+
+	if _, ok := byDevice["/dev/mapper/vg--sys-root"]; ok {
+		t.Error("regular LV is assigned to a thin pool")
+	}
+}
@@ -0,0 +1,206 @@
+package measurements
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// CA pool modes
+const (
+	CAModeReplace = "replace"
+	CAModeMerge   = "merge"
+)
+
+// TLSClient configures client certificates and trusted CAs of TLS clients
+type TLSClient struct {
+	insecure bool
+	certfile string
+	keyfile  string
+	passfile string
+	cafile   string
+	cadir    string
+	caMode   string
+}
+
+func (conf *TLSClient) SetFlags(flags *pflag.FlagSet) {
+	flags.BoolVarP(&conf.insecure, "insecure", "k", false, "Enable insecure connections")
+	flags.StringVarP(&conf.certfile, "cert", "c", "", "Certificate file")
+	flags.StringVar(&conf.keyfile, "key", "", "Private key file of certificate (default: --cert)")
+	flags.StringVar(&conf.passfile, "key-pass-file", "", "File containing passphrase of encrypted private key")
+	flags.StringVarP(&conf.cafile, "ca", "C", "", "CA Certificate file")
+	flags.StringVar(&conf.cadir, "ca-dir", "", "Directory of CA certificate files")
+	flags.StringVar(&conf.caMode, "ca-mode", CAModeReplace, "Trust CA certificates instead of system CAs (replace),"+
+		" or besides them (merge)")
+}
+
+func (conf *TLSClient) Check() error {
+	if conf.caMode != CAModeReplace && conf.caMode != CAModeMerge {
+		return fmt.Errorf("--ca-mode should be %s or %s", CAModeReplace, CAModeMerge)
+	}
+
+	if len(conf.certfile) == 0 && (len(conf.keyfile) != 0 || len(conf.passfile) != 0) {
+		return errors.New("--key and --key-pass-file require --cert")
+	}
+
+	return nil
+}
+
+// Config returns TLS client configuration
+func (conf *TLSClient) Config() (*tls.Config, error) {
+	var err error
+
+	tlsconfig := &tls.Config{InsecureSkipVerify: conf.insecure}
+
+	if len(conf.certfile) != 0 {
+		cert, err := conf.certificate()
+		if err != nil {
+			return nil, err
+		}
+
+		tlsconfig.Certificates = []tls.Certificate{cert}
+	}
+
+	tlsconfig.RootCAs, err = conf.certPool()
+	if err != nil {
+		return nil, err
+	}
+
+	return tlsconfig, nil
+}
+
+func (conf *TLSClient) certificate() (tls.Certificate, error) {
+	keyfile := conf.keyfile
+	if len(keyfile) == 0 {
+		keyfile = conf.certfile
+	}
+
+	certPEM, err := ioutil.ReadFile(conf.certfile)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("cannot load --cert: %w", err)
+	}
+
+	keyPEM, err := ioutil.ReadFile(keyfile)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("cannot load --key: %w", err)
+	}
+
+	if len(conf.passfile) != 0 {
+		keyPEM, err = conf.decryptKey(keyPEM)
+		if err != nil {
+			return tls.Certificate{}, err
+		}
+	}
+
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("cannot load client certificate: %w", err)
+	}
+
+	return cert, nil
+}
+
+// decryptKey decrypts the first legacy encrypted (RFC 1423) PEM private key
+// block, like the ones created by `openssl genrsa -aes256`. Legacy PEM
+// encryption is deprecated, but it has no replacement in the standard
+// library. PKCS#8 encrypted keys are not supported. Contents are returned
+// as is if there are no encrypted keys.
+func (conf *TLSClient) decryptKey(contents []byte) ([]byte, error) {
+	passphrase, err := ioutil.ReadFile(conf.passfile)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load --key-pass-file: %w", err)
+	}
+
+	passphrase = bytes.TrimRight(passphrase, "\r\n")
+
+	for rest := contents; ; {
+		var block *pem.Block
+
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			return contents, nil
+		}
+
+		if block.Type == "ENCRYPTED PRIVATE KEY" {
+			return nil, errors.New("PKCS#8 encrypted private keys are not supported")
+		}
+
+		//nolint:staticcheck
+		if !strings.HasSuffix(block.Type, "PRIVATE KEY") || !x509.IsEncryptedPEMBlock(block) {
+			continue
+		}
+
+		//nolint:staticcheck
+		der, err := x509.DecryptPEMBlock(block, passphrase)
+		if err != nil {
+			return nil, fmt.Errorf("cannot decrypt private key: %w", err)
+		}
+
+		return pem.EncodeToMemory(&pem.Block{Type: block.Type, Bytes: der}), nil
+	}
+}
+
+// certPool returns trusted CA pool, or nil if system pool should be used
+func (conf *TLSClient) certPool() (*x509.CertPool, error) {
+	if len(conf.cafile) == 0 && len(conf.cadir) == 0 {
+		return nil, nil
+	}
+
+	certpool := x509.NewCertPool()
+
+	if conf.caMode == CAModeMerge {
+		syspool, err := x509.SystemCertPool()
+		if err != nil {
+			return nil, fmt.Errorf("cannot load system CA certificates: %w", err)
+		}
+
+		certpool = syspool
+	}
+
+	files := []string{}
+
+	if len(conf.cafile) != 0 {
+		files = append(files, conf.cafile)
+	}
+
+	if len(conf.cadir) != 0 {
+		entries, err := os.ReadDir(conf.cadir)
+		if err != nil {
+			return nil, fmt.Errorf("cannot load --ca-dir: %w", err)
+		}
+
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				files = append(files, filepath.Join(conf.cadir, entry.Name()))
+			}
+		}
+	}
+
+	loaded := 0
+
+	for _, file := range files {
+		contents, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("cannot load CA certificate: %w", err)
+		}
+
+		if certpool.AppendCertsFromPEM(contents) {
+			loaded++
+		}
+	}
+
+	if loaded == 0 {
+		return nil, errors.New("no CA certificates found")
+	}
+
+	return certpool, nil
+}
@@ -0,0 +1,212 @@
+package measurements
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testCA writes a self-signed CA certificate and its key into dir, and
+// returns the certificate
+func testCA(t *testing.T, dir, name string) *x509.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writePEM(t, filepath.Join(dir, name+".crt"), &pem.Block{Type: "CERTIFICATE", Bytes: der})
+	writePEM(t, filepath.Join(dir, name+".key"), &pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+
+	return cert
+}
+
+func writePEM(t *testing.T, path string, block *pem.Block) {
+	if err := os.WriteFile(path, pem.EncodeToMemory(block), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTLSClient_Config_certPool(t *testing.T) {
+	dir := t.TempDir()
+	cadir := filepath.Join(dir, "cas")
+
+	if err := os.Mkdir(cadir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	first := testCA(t, dir, "first")
+	second := testCA(t, cadir, "second")
+	tests := []struct {
+		name    string
+		conf    TLSClient
+		trusted []*x509.Certificate
+		wantErr bool
+	}{
+		{"CA file", TLSClient{cafile: filepath.Join(dir, "first.crt")}, []*x509.Certificate{first}, false},
+		{"CA dir", TLSClient{cadir: cadir}, []*x509.Certificate{second}, false},
+		{
+			"CA file and dir",
+			TLSClient{cafile: filepath.Join(dir, "first.crt"), cadir: cadir},
+			[]*x509.Certificate{first, second},
+			false,
+		},
+		{"no CA certificates", TLSClient{cafile: filepath.Join(dir, "first.key")}, nil, true},
+		{"missing CA file", TLSClient{cafile: filepath.Join(dir, "missing.crt")}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := tt.conf
+			conf.caMode = CAModeReplace
+
+			tlsconfig, err := conf.Config()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Config() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if err != nil {
+				return
+			}
+
+			if tlsconfig.RootCAs == nil {
+				t.Fatal("Config() has no RootCAs")
+			}
+
+			for _, cert := range tt.trusted {
+				if _, err := cert.Verify(x509.VerifyOptions{Roots: tlsconfig.RootCAs}); err != nil {
+					t.Errorf("%s is not trusted: %v", cert.Subject.CommonName, err)
+				}
+			}
+		})
+	}
+}
+
+func TestTLSClient_Config_certificate(t *testing.T) {
+	dir := t.TempDir()
+
+	testCA(t, dir, "client")
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "encrypted"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &rsaKey.PublicKey, rsaKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//nolint:staticcheck
+	block, err := x509.EncryptPEMBlock(
+		rand.Reader,
+		"RSA PRIVATE KEY",
+		x509.MarshalPKCS1PrivateKey(rsaKey),
+		[]byte("secret"),
+		x509.PEMCipherAES256,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writePEM(t, filepath.Join(dir, "encrypted.crt"), &pem.Block{Type: "CERTIFICATE", Bytes: der})
+	writePEM(t, filepath.Join(dir, "encrypted.key"), block)
+
+	for name, content := range map[string]string{"pass": "secret\n", "wrongpass": "wrong\n"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		conf    TLSClient
+		wantErr bool
+	}{
+		{
+			"separate key",
+			TLSClient{certfile: filepath.Join(dir, "client.crt"), keyfile: filepath.Join(dir, "client.key")},
+			false,
+		},
+		{"key in cert file", TLSClient{certfile: filepath.Join(dir, "client.crt")}, true},
+		{
+			"encrypted key",
+			TLSClient{
+				certfile: filepath.Join(dir, "encrypted.crt"),
+				keyfile:  filepath.Join(dir, "encrypted.key"),
+				passfile: filepath.Join(dir, "pass"),
+			},
+			false,
+		},
+		{
+			"wrong passphrase",
+			TLSClient{
+				certfile: filepath.Join(dir, "encrypted.crt"),
+				keyfile:  filepath.Join(dir, "encrypted.key"),
+				passfile: filepath.Join(dir, "wrongpass"),
+			},
+			true,
+		},
+		{
+			"encrypted key without passphrase",
+			TLSClient{certfile: filepath.Join(dir, "encrypted.crt"), keyfile: filepath.Join(dir, "encrypted.key")},
+			true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := tt.conf
+			conf.caMode = CAModeReplace
+
+			tlsconfig, err := conf.Config()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Config() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if err == nil && len(tlsconfig.Certificates) != 1 {
+				t.Errorf("Config() has %d certificates, want 1", len(tlsconfig.Certificates))
+			}
+		})
+	}
+}
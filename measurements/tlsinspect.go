@@ -0,0 +1,244 @@
+package measurements
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/julian7/sensulib"
+	"github.com/spf13/pflag"
+)
+
+// TLS problem classes
+const (
+	TLSChain     = "chain"
+	TLSHostname  = "hostname"
+	TLSKey       = "key"
+	TLSSignature = "signature"
+	TLSProtocol  = "protocol"
+)
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+var weakSignatures = map[x509.SignatureAlgorithm]bool{
+	x509.MD2WithRSA:    true,
+	x509.MD5WithRSA:    true,
+	x509.SHA1WithRSA:   true,
+	x509.DSAWithSHA1:   true,
+	x509.ECDSAWithSHA1: true,
+}
+
+// TLSProblem is an issue found while inspecting a TLS connection
+type TLSProblem struct {
+	Class   string
+	Message string
+}
+
+// TLSInspector checks TLS connections for certificate chain, hostname, key,
+// signature, and protocol problems
+type TLSInspector struct {
+	enabled     bool
+	minVersionS string
+	minVersion  uint16
+	severitiesS []string
+	severities  map[string]string
+}
+
+func (conf *TLSInspector) SetFlags(flags *pflag.FlagSet) {
+	flags.BoolVar(&conf.enabled, "inspect", false, "Inspect TLS certificate chain, keys, signatures, and protocol")
+	flags.StringVar(&conf.minVersionS, "min-tls", "1.2", "Minimum TLS version for --inspect")
+	flags.StringSliceVar(&conf.severitiesS, "tls-severity", nil, "Alert level of TLS problem classes in CLASS=LEVEL"+
+		" form; classes: chain, hostname, key, signature, protocol; levels: ok, warn, crit")
+}
+
+func (conf *TLSInspector) Check() error {
+	var ok bool
+
+	conf.minVersion, ok = tlsVersions[conf.minVersionS]
+	if !ok {
+		return fmt.Errorf("--min-tls should be one of 1.0, 1.1, 1.2, or 1.3")
+	}
+
+	conf.severities = map[string]string{
+		TLSChain:     "crit",
+		TLSHostname:  "crit",
+		TLSKey:       "warn",
+		TLSSignature: "warn",
+		TLSProtocol:  "warn",
+	}
+
+	for _, item := range conf.severitiesS {
+		items := strings.SplitN(item, "=", 2)
+		if len(items) != 2 {
+			return fmt.Errorf("--tls-severity %q should be in CLASS=LEVEL form", item)
+		}
+
+		if _, ok := conf.severities[items[0]]; !ok {
+			return fmt.Errorf("--tls-severity %q has unknown class", item)
+		}
+
+		switch items[1] {
+		case "ok", "warn", "crit":
+		default:
+			return fmt.Errorf("--tls-severity %q has unknown level", item)
+		}
+
+		conf.severities[items[0]] = items[1]
+	}
+
+	return nil
+}
+
+// Enabled returns true if TLS inspection is requested
+func (conf *TLSInspector) Enabled() bool {
+	return conf.enabled
+}
+
+// Fatal returns true if a problem is critical, and it means the connection
+// cannot be trusted
+func (conf *TLSInspector) Fatal(problem TLSProblem) bool {
+	return (problem.Class == TLSChain || problem.Class == TLSHostname) && conf.severities[problem.Class] == "crit"
+}
+
+// Inspect returns problems of a TLS connection. Chain is checked against roots
+// (system CAs, if nil), and the certificate is checked against host, unless
+// verify is false. Host is provided explicitly, as IP addresses are not sent
+// in SNI, and they are missing from the connection state.
+func (conf *TLSInspector) Inspect(cs tls.ConnectionState, host string, roots *x509.CertPool, verify bool) []TLSProblem {
+	var problems []TLSProblem
+
+	add := func(class, format string, args ...interface{}) {
+		problems = append(problems, TLSProblem{Class: class, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if len(cs.PeerCertificates) == 0 {
+		add(TLSChain, "no peer certificates")
+
+		return problems
+	}
+
+	leaf := cs.PeerCertificates[0]
+
+	if verify {
+		intermediates := x509.NewCertPool()
+		for _, cert := range cs.PeerCertificates[1:] {
+			intermediates.AddCert(cert)
+		}
+
+		if _, err := leaf.Verify(x509.VerifyOptions{Roots: roots, Intermediates: intermediates}); err != nil {
+			add(TLSChain, "invalid chain: %v", err)
+		}
+
+		if err := leaf.VerifyHostname(host); err != nil {
+			add(TLSHostname, "%v", err)
+		}
+	}
+
+	for _, cert := range cs.PeerCertificates {
+		if weak, desc := weakKey(cert); weak {
+			add(TLSKey, "%s has weak %s key", cert.Subject.CommonName, desc)
+		}
+
+		// signatures of self-signed certificates are not relevant
+		if weakSignatures[cert.SignatureAlgorithm] && !bytes.Equal(cert.RawSubject, cert.RawIssuer) {
+			add(TLSSignature, "%s is signed with %s", cert.Subject.CommonName, cert.SignatureAlgorithm)
+		}
+	}
+
+	if cs.Version < conf.minVersion {
+		add(TLSProtocol, "%s negotiated, expected at least TLS %s", TLSVersionName(cs.Version), conf.minVersionS)
+	}
+
+	for _, suite := range tls.InsecureCipherSuites() {
+		if suite.ID == cs.CipherSuite {
+			add(TLSProtocol, "insecure cipher suite %s negotiated", suite.Name)
+		}
+	}
+
+	return problems
+}
+
+// Errors converts problems to sensulib errors, based on their class'
+// severity
+func (conf *TLSInspector) Errors(problems []TLSProblem) []*sensulib.Error {
+	var ret []*sensulib.Error
+
+	for _, problem := range problems {
+		err := fmt.Errorf("TLS %s problem: %s", problem.Class, problem.Message)
+
+		switch conf.severities[problem.Class] {
+		case "crit":
+			ret = append(ret, sensulib.Crit(err))
+		case "warn":
+			ret = append(ret, sensulib.Warn(err))
+		}
+	}
+
+	return ret
+}
+
+// TLSVersionName returns human readable name of a TLS version
+func TLSVersionName(version uint16) string {
+	for name, id := range tlsVersions {
+		if id == version {
+			return "TLS " + name
+		}
+	}
+
+	return fmt.Sprintf("unknown TLS version 0x%04x", version)
+}
+
+// KeyDescription returns type and size of a certificate's public key
+func KeyDescription(cert *x509.Certificate) string {
+	_, desc := weakKey(cert)
+
+	return desc
+}
+
+func weakKey(cert *x509.Certificate) (bool, string) {
+	switch key := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		return key.N.BitLen() < 2048, fmt.Sprintf("RSA %d", key.N.BitLen())
+	case *ecdsa.PublicKey:
+		return key.Curve.Params().BitSize < 256, fmt.Sprintf("ECDSA %s", key.Curve.Params().Name)
+	case ed25519.PublicKey:
+		return false, "Ed25519"
+	}
+
+	return true, cert.PublicKeyAlgorithm.String()
+}
+
+// ChainSummary returns a line of description for each certificate of a chain
+func ChainSummary(certs []*x509.Certificate) []string {
+	lines := make([]string, len(certs))
+
+	for i, cert := range certs {
+		lines[i] = fmt.Sprintf(
+			"%d: %s (issuer: %s; %s key; %s; expires in %d days)",
+			i,
+			cert.Subject.CommonName,
+			cert.Issuer.CommonName,
+			KeyDescription(cert),
+			cert.SignatureAlgorithm,
+			DaysLeft(cert),
+		)
+	}
+
+	return lines
+}
+
+// DaysLeft returns number of days until the certificate expires
+func DaysLeft(cert *x509.Certificate) int {
+	return int(time.Until(cert.NotAfter).Hours() / 24)
+}
@@ -0,0 +1,265 @@
+package measurements
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/go-test/deep"
+)
+
+// issueCert creates a certificate signed by parent, or a self-signed one if
+// parent is nil
+func issueCert(
+	t *testing.T,
+	template *x509.Certificate,
+	parent *x509.Certificate,
+	parentKey crypto.Signer,
+	curve elliptic.Curve,
+) (*x509.Certificate, crypto.Signer) {
+	key, err := ecdsa.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template.SerialNumber = big.NewInt(time.Now().UnixNano())
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(24 * time.Hour)
+
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return cert, key
+}
+
+func TestTLSInspector_Inspect(t *testing.T) {
+	caTemplate := func(name string) *x509.Certificate {
+		return &x509.Certificate{
+			Subject:               pkix.Name{CommonName: name},
+			IsCA:                  true,
+			BasicConstraintsValid: true,
+			KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		}
+	}
+	leafTemplate := func(sigalg x509.SignatureAlgorithm) *x509.Certificate {
+		return &x509.Certificate{
+			Subject:            pkix.Name{CommonName: "localhost"},
+			DNSNames:           []string{"localhost"},
+			SignatureAlgorithm: sigalg,
+			KeyUsage:           x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:        []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		}
+	}
+
+	ca, caKey := issueCert(t, caTemplate("ca"), nil, nil, elliptic.P256())
+	other, _ := issueCert(t, caTemplate("other"), nil, nil, elliptic.P256())
+	leaf, _ := issueCert(t, leafTemplate(x509.ECDSAWithSHA256), ca, caKey, elliptic.P256())
+	weakLeaf, _ := issueCert(t, leafTemplate(x509.ECDSAWithSHA256), ca, caKey, elliptic.P224())
+	sha1Leaf, _ := issueCert(t, leafTemplate(x509.ECDSAWithSHA1), ca, caKey, elliptic.P256())
+
+	ipTemplate := leafTemplate(x509.ECDSAWithSHA256)
+	ipTemplate.IPAddresses = []net.IP{net.ParseIP("10.0.0.1")}
+	ipLeaf, _ := issueCert(t, ipTemplate, ca, caKey, elliptic.P256())
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+
+	otherRoots := x509.NewCertPool()
+	otherRoots.AddCert(other)
+
+	// server name is left empty, like for IP addresses, which are not sent in SNI
+	state := func(leaf *x509.Certificate, version, suite uint16) tls.ConnectionState {
+		return tls.ConnectionState{
+			Version:          version,
+			CipherSuite:      suite,
+			PeerCertificates: []*x509.Certificate{leaf, ca},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		host   string
+		cs     tls.ConnectionState
+		roots  *x509.CertPool
+		verify bool
+		want   []string
+	}{
+		{
+			"valid",
+			"localhost",
+			state(leaf, tls.VersionTLS13, tls.TLS_AES_128_GCM_SHA256),
+			roots,
+			true,
+			nil,
+		},
+		{
+			"no certificates",
+			"localhost",
+			tls.ConnectionState{Version: tls.VersionTLS13},
+			roots,
+			true,
+			[]string{TLSChain},
+		},
+		{
+			"untrusted",
+			"localhost",
+			state(leaf, tls.VersionTLS13, tls.TLS_AES_128_GCM_SHA256),
+			otherRoots,
+			true,
+			[]string{TLSChain},
+		},
+		{
+			"untrusted without verification",
+			"localhost",
+			state(leaf, tls.VersionTLS13, tls.TLS_AES_128_GCM_SHA256),
+			otherRoots,
+			false,
+			nil,
+		},
+		{
+			"hostname mismatch",
+			"example.com",
+			state(leaf, tls.VersionTLS13, tls.TLS_AES_128_GCM_SHA256),
+			roots,
+			true,
+			[]string{TLSHostname},
+		},
+		{
+			"IP address",
+			"10.0.0.1",
+			state(ipLeaf, tls.VersionTLS13, tls.TLS_AES_128_GCM_SHA256),
+			roots,
+			true,
+			nil,
+		},
+		{
+			"IP address mismatch",
+			"10.0.0.2",
+			state(ipLeaf, tls.VersionTLS13, tls.TLS_AES_128_GCM_SHA256),
+			roots,
+			true,
+			[]string{TLSHostname},
+		},
+		{
+			"weak key",
+			"localhost",
+			state(weakLeaf, tls.VersionTLS13, tls.TLS_AES_128_GCM_SHA256),
+			roots,
+			true,
+			[]string{TLSKey},
+		},
+		{
+			"SHA-1 signature",
+			"localhost",
+			state(sha1Leaf, tls.VersionTLS13, tls.TLS_AES_128_GCM_SHA256),
+			roots,
+			false,
+			[]string{TLSSignature},
+		},
+		{
+			"old protocol",
+			"localhost",
+			state(leaf, tls.VersionTLS11, tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA),
+			roots,
+			true,
+			[]string{TLSProtocol},
+		},
+		{
+			"insecure cipher suite",
+			"localhost",
+			state(leaf, tls.VersionTLS12, tls.TLS_ECDHE_ECDSA_WITH_RC4_128_SHA),
+			roots,
+			true,
+			[]string{TLSProtocol},
+		},
+	}
+
+	conf := &TLSInspector{minVersionS: "1.2"}
+	if err := conf.Check(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			var classes []string
+
+			for _, problem := range conf.Inspect(tt.cs, tt.host, tt.roots, tt.verify) {
+				classes = append(classes, problem.Class)
+			}
+
+			if diff := deep.Equal(classes, tt.want); diff != nil {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestTLSInspector_Check(t *testing.T) {
+	tests := []struct {
+		name       string
+		severities []string
+		minVersion string
+		want       map[string]string
+		wantErr    bool
+	}{
+		{
+			"defaults",
+			nil,
+			"1.2",
+			map[string]string{"chain": "crit", "hostname": "crit", "key": "warn", "signature": "warn", "protocol": "warn"},
+			false,
+		},
+		{
+			"overrides",
+			[]string{"hostname=warn", "protocol=ok"},
+			"1.3",
+			map[string]string{"chain": "crit", "hostname": "warn", "key": "warn", "signature": "warn", "protocol": "ok"},
+			false,
+		},
+		{"unknown class", []string{"cipher=warn"}, "1.2", nil, true},
+		{"unknown level", []string{"key=fatal"}, "1.2", nil, true},
+		{"invalid form", []string{"key"}, "1.2", nil, true},
+		{"unknown version", nil, "1.4", nil, true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			conf := &TLSInspector{minVersionS: tt.minVersion, severitiesS: tt.severities}
+
+			err := conf.Check()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("TLSInspector.Check() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			if diff := deep.Equal(conf.severities, tt.want); diff != nil {
+				t.Error(diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package measurements
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ZPool contains health and capacity information of a ZFS pool
+type ZPool struct {
+	Name          string
+	Size          uint64
+	Allocated     uint64
+	Free          uint64
+	Fragmentation float64 // -1 if unknown
+	Capacity      float64
+	Health        string
+	Scrubbed      time.Time // zero if never scrubbed
+	Scrubbing     bool
+	ScrubErrors   uint64
+	DataErrors    string // empty if there are no known data errors
+}
+
+var (
+	zpoolScrubRe    = regexp.MustCompile(`^scrub repaired .* with (\d+) errors on (.+)$`)
+	zpoolScrubbing  = regexp.MustCompile(`^scrub in progress`)
+	zpoolNoErrorsRe = regexp.MustCompile(`^No known data errors`)
+)
+
+// ZPools reads ZFS pool information using the zpool command
+func ZPools(run CommandRunner, zpool string) ([]*ZPool, error) {
+	out, err := run(zpool, "list", "-Hp", "-o", "name,size,allocated,free,fragmentation,capacity,health")
+	if err != nil {
+		return nil, err
+	}
+
+	pools, err := parseZpoolList(out)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(pools) == 0 {
+		return pools, nil
+	}
+
+	out, err = run(zpool, "status")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := parseZpoolStatus(out, pools, time.Local); err != nil {
+		return nil, err
+	}
+
+	return pools, nil
+}
+
+func parseZpoolList(out []byte) ([]*ZPool, error) {
+	var pools []*ZPool
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if len(strings.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		if len(fields) != 7 {
+			return nil, fmt.Errorf("parsing zpool list: unexpected line %q", line)
+		}
+
+		pool := &ZPool{Name: fields[0], Health: fields[6], Fragmentation: -1}
+
+		for _, item := range []struct {
+			source string
+			target *uint64
+		}{
+			{fields[1], &pool.Size},
+			{fields[2], &pool.Allocated},
+			{fields[3], &pool.Free},
+		} {
+			val, err := strconv.ParseUint(item.source, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("parsing zpool list of %s: %w", pool.Name, err)
+			}
+
+			*item.target = val
+		}
+
+		if fields[4] != "-" {
+			frag, err := strconv.ParseFloat(strings.TrimSuffix(fields[4], "%"), 64)
+			if err != nil {
+				return nil, fmt.Errorf("parsing zpool list of %s: %w", pool.Name, err)
+			}
+
+			pool.Fragmentation = frag
+		}
+
+		capacity, err := strconv.ParseFloat(strings.TrimSuffix(fields[5], "%"), 64)
+		if err != nil {
+			return nil, fmt.Errorf("parsing zpool list of %s: %w", pool.Name, err)
+		}
+
+		pool.Capacity = capacity
+		pools = append(pools, pool)
+	}
+
+	return pools, scanner.Err()
+}
+
+func parseZpoolStatus(out []byte, pools []*ZPool, loc *time.Location) error {
+	var pool *ZPool
+
+	byName := make(map[string]*ZPool, len(pools))
+	for _, pool := range pools {
+		byName[pool.Name] = pool
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		key, value, ok := statusField(scanner.Text())
+		if !ok {
+			continue
+		}
+
+		if key == "pool" {
+			pool = byName[value]
+			continue
+		}
+
+		if pool == nil {
+			continue
+		}
+
+		switch key {
+		case "scan":
+			if err := pool.parseScan(value, loc); err != nil {
+				return err
+			}
+		case "errors":
+			if !zpoolNoErrorsRe.MatchString(value) {
+				pool.DataErrors = value
+			}
+		}
+	}
+
+	return scanner.Err()
+}
+
+// statusField parses "key: value" lines of zpool status output
+func statusField(line string) (string, string, bool) {
+	items := strings.SplitN(strings.TrimSpace(line), ":", 2)
+	if len(items) != 2 || strings.ContainsAny(items[0], " \t") {
+		return "", "", false
+	}
+
+	return items[0], strings.TrimSpace(items[1]), true
+}
+
+func (pool *ZPool) parseScan(scan string, loc *time.Location) error {
+	if zpoolScrubbing.MatchString(scan) {
+		pool.Scrubbing = true
+		return nil
+	}
+
+	matches := zpoolScrubRe.FindStringSubmatch(scan)
+	if matches == nil {
+		return nil
+	}
+
+	errs, err := strconv.ParseUint(matches[1], 10, 64)
+	if err != nil {
+		return fmt.Errorf("parsing scrub errors of %s: %w", pool.Name, err)
+	}
+
+	scrubbed, err := time.ParseInLocation(time.ANSIC, strings.Join(strings.Fields(matches[2]), " "), loc)
+	if err != nil {
+		return fmt.Errorf("parsing scrub time of %s: %w", pool.Name, err)
+	}
+
+	pool.ScrubErrors = errs
+	pool.Scrubbed = scrubbed
+
+	return nil
+}
@@ -0,0 +1,91 @@
+package measurements
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-test/deep"
+)
+
+func fixtureRunner(t *testing.T, fixtures map[string]string) CommandRunner {
+	t.Helper()
+
+	return func(name string, args ...string) ([]byte, error) {
+		if len(args) == 0 {
+			return nil, errors.New("no subcommand")
+		}
+
+		fixture, ok := fixtures[args[0]]
+		if !ok {
+			return nil, errors.New("unexpected subcommand " + args[0])
+		}
+
+		return os.ReadFile(filepath.Join("testdata", fixture))
+	}
+}
+
+func TestZPools(t *testing.T) {
+	run := fixtureRunner(t, map[string]string{
+		"list":   "zpool-list.txt",
+		"status": "zpool-status.txt",
+	})
+
+	pools, err := ZPools(run, "zpool")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []*ZPool{
+		{
+			Name:          "rpool",
+			Size:          254476812288,
+			Allocated:     92175339520,
+			Free:          162301472768,
+			Fragmentation: 12,
+			Capacity:      36,
+			Health:        "ONLINE",
+			Scrubbed:      time.Date(2022, 2, 13, 0, 29, 13, 0, time.Local),
+		},
+		{
+			Name:          "tank",
+			Size:          7971459301376,
+			Allocated:     7173837930496,
+			Free:          797621370880,
+			Fragmentation: 47,
+			Capacity:      89,
+			Health:        "DEGRADED",
+			Scrubbed:      time.Date(2022, 2, 6, 0, 42, 1, 0, time.Local),
+			ScrubErrors:   3,
+			DataErrors:    "2 data errors, use '-v' for a list",
+		},
+		{
+			Name:          "backup",
+			Size:          3985729650688,
+			Allocated:     1048576,
+			Free:          3985728602112,
+			Fragmentation: -1,
+			Capacity:      0,
+			Health:        "ONLINE",
+		},
+	}
+
+	if diff := deep.Equal(want, pools); diff != nil {
+		t.Error(diff)
+	}
+}
+
+func TestZPools_scrubInProgress(t *testing.T) {
+	pools := []*ZPool{{Name: "tank"}}
+	status := []byte("  pool: tank\n state: ONLINE\n  scan: scrub in progress since Sun Feb 13 00:24:01 2022\n")
+
+	if err := parseZpoolStatus(status, pools, time.UTC); err != nil {
+		t.Fatal(err)
+	}
+
+	if !pools[0].Scrubbing {
+		t.Error("scrub in progress is not detected")
+	}
+}